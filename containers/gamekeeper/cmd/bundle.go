@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/config"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/output"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/server"
+	"github.com/spf13/cobra"
+)
+
+var bundlePath string
+
+var bundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Create and import offline installation bundles",
+	Long: `Create and import offline installation bundles.
+
+A bundle holds the installed game build, mods, downloader and rendered config
+with a manifest of checksums, so servers can be installed on networks that
+can't reach CurseForge, Steam or the game's download servers.`,
+}
+
+var bundleCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Write the current installation to a bundle",
+	RunE:  runBundleCreate,
+}
+
+var bundleImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Install from a bundle without network access",
+	RunE:  runBundleImport,
+}
+
+func init() {
+	for _, c := range []*cobra.Command{bundleCreateCmd, bundleImportCmd} {
+		addGameFlag(c)
+		c.Flags().StringVar(&configPath, "config", "/home/kubelize/config-data/config-values.yaml", "Path to config values file")
+	}
+	bundleCreateCmd.Flags().StringVarP(&bundlePath, "output", "o", "", "Bundle file, .tar.zst or .tar.gz (default: <game>-bundle.tar.zst)")
+	bundleImportCmd.Flags().StringVarP(&bundlePath, "file", "f", "", "Bundle file to import")
+	bundleImportCmd.MarkFlagRequired("file")
+
+	bundleCmd.AddCommand(bundleCreateCmd)
+	bundleCmd.AddCommand(bundleImportCmd)
+}
+
+// bundler creates the manager for --game and checks it supports bundles
+func bundler() (server.Bundler, error) {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	mgr, err := server.NewManager(gameType, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create server manager: %w", err)
+	}
+
+	b, ok := mgr.(server.Bundler)
+	if !ok {
+		return nil, fmt.Errorf("bundles are not supported for %s", gameType)
+	}
+	return b, nil
+}
+
+func runBundleCreate(cmd *cobra.Command, args []string) error {
+	b, err := bundler()
+	if err != nil {
+		return err
+	}
+
+	dest := bundlePath
+	if dest == "" {
+		dest = fmt.Sprintf("%s-bundle.tar.zst", gameType)
+	}
+
+	output.Section("Creating bundle")
+	if err := b.CreateBundle(dest); err != nil {
+		return fmt.Errorf("bundle creation failed: %w", err)
+	}
+
+	fmt.Printf("✅ Bundle written to %s\n", dest)
+	return nil
+}
+
+func runBundleImport(cmd *cobra.Command, args []string) error {
+	b, err := bundler()
+	if err != nil {
+		return err
+	}
+
+	output.Section("Importing bundle")
+	if err := b.ImportBundle(bundlePath); err != nil {
+		return fmt.Errorf("bundle import failed: %w", err)
+	}
+
+	fmt.Println("✅ Bundle imported")
+	return nil
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/cache"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/config"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cacheDir    string
+	cacheListen string
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the shared artifact cache",
+}
+
+var cacheServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the artifact cache as a download mirror",
+	Long: `Serve the artifact cache over HTTP so other pods can use it as a mirror.
+
+Point game server pods at it with ARTIFACT_MIRROR_URL; anything they download
+from the origin is added to their own ARTIFACT_CACHE_DIR, so a mirror sharing
+that volume fills up as pods fetch artifacts.`,
+	RunE: runCacheServe,
+}
+
+func init() {
+	cacheServeCmd.Flags().StringVar(&configPath, "config", "/home/kubelize/config-data/config-values.yaml", "Path to config values file")
+	cacheServeCmd.Flags().StringVar(&cacheDir, "dir", "", "Cache directory (default: ARTIFACT_CACHE_DIR)")
+	cacheServeCmd.Flags().StringVar(&cacheListen, "listen", ":8080", "Address to listen on")
+
+	cacheCmd.AddCommand(cacheServeCmd)
+}
+
+func runCacheServe(cmd *cobra.Command, args []string) error {
+	dir := cacheDir
+	if dir == "" {
+		cfg, err := config.Load(configPath)
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		dir = cfg.GetString("ARTIFACT_CACHE_DIR", "")
+	}
+	if dir == "" {
+		return fmt.Errorf("no cache directory: set --dir or ARTIFACT_CACHE_DIR")
+	}
+
+	output.Section("Artifact cache")
+	output.Info(fmt.Sprintf("Serving %s on %s", dir, cacheListen))
+	return http.ListenAndServe(cacheListen, cache.Open(dir).Handler())
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/config"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/configfile"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/render"
+	"github.com/spf13/cobra"
+)
+
+var (
+	templatePath string
+	renderOutput string
+	datasources  []string
+	renderMode   string
+	stateDir     string
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Work with game configuration templates",
+}
+
+var configRenderCmd = &cobra.Command{
+	Use:   "render",
+	Short: "Render a gomplate-style config template",
+	Long: `Render a serverconfig template with the built-in gomplate-compatible engine.
+
+The "config" datasource holds the config values with environment variables
+applied, and "password" reads SERVER_PASSWORD_FILE (the chart's password
+secret). More datasources can be added with -d alias=path. Templates may use
+getenv, datasource, ds, datasourceExists, default and toJSON.
+
+With -o, --mode decides how an existing file is treated: always-overwrite
+replaces it, seed-once leaves it alone, and managed-keys merges only the keys
+that changed in config since the last render, keeping edits made in-game. The
+last rendered output is kept in --state-dir for that merge.`,
+	RunE: runConfigRender,
+}
+
+func init() {
+	configRenderCmd.Flags().StringVar(&configPath, "config", "/home/kubelize/config-data/config-values.yaml", "Path to config values file")
+	configRenderCmd.Flags().StringVarP(&templatePath, "file", "f", "/usr/local/share/game-templates/serverconfig.template", "Template to render")
+	configRenderCmd.Flags().StringVarP(&renderOutput, "out", "o", "", "Output file (default: stdout)")
+	configRenderCmd.Flags().StringArrayVarP(&datasources, "datasource", "d", nil, "Datasource as alias=path to a YAML or JSON file")
+	configRenderCmd.Flags().StringVar(&renderMode, "mode", string(configfile.AlwaysOverwrite), "seed-once, always-overwrite or managed-keys")
+	configRenderCmd.Flags().StringVar(&stateDir, "state-dir", "/home/kubelize/server/.gamekeeper", "Where the last rendered output is kept")
+
+	configCmd.AddCommand(configRenderCmd)
+}
+
+func runConfigRender(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	mode, err := configfile.ParseMode(renderMode)
+	if err != nil {
+		return err
+	}
+
+	r := render.New(cfg)
+	for _, ds := range datasources {
+		alias, path, ok := strings.Cut(ds, "=")
+		if !ok || alias == "" || path == "" {
+			return fmt.Errorf("invalid datasource %q: expected alias=path", ds)
+		}
+		r.AddFile(alias, path)
+	}
+
+	data, err := r.RenderFile(templatePath)
+	if err != nil {
+		return err
+	}
+
+	if renderOutput == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+
+	file := configfile.File{Path: renderOutput, Mode: mode, StateDir: stateDir}
+	result, err := file.Write(data)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%s: %s\n", renderOutput, result)
+	return nil
+}
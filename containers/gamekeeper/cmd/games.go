@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/config"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/gamedef"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/server"
+	"github.com/spf13/cobra"
+)
+
+var gamesCmd = &cobra.Command{
+	Use:   "games",
+	Short: "List the supported games",
+	Long: `List the games gamekeeper can run: games with a dedicated manager and
+games run from definitions, built in or in GAME_DEFINITIONS_DIR.`,
+}
+
+var gamesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the supported games",
+	Args:  cobra.NoArgs,
+	RunE:  runGamesList,
+}
+
+var gamesShowCmd = &cobra.Command{
+	Use:   "show <game>",
+	Short: "Show a game's aliases, capabilities, defaults and definition",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runGamesShow,
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return completeGames(cmd, args, toComplete)
+	},
+}
+
+func init() {
+	gamesCmd.AddCommand(gamesListCmd)
+	gamesCmd.AddCommand(gamesShowCmd)
+}
+
+// addGameFlag adds the required --game flag; its value is checked against
+// the game registry and replaced by the game's canonical name
+func addGameFlag(c *cobra.Command) {
+	c.Flags().StringVar(&gameType, "game", "", gameFlagUsage())
+	c.MarkFlagRequired("game")
+	c.RegisterFlagCompletionFunc("game", completeGames)
+}
+
+// resolveGameFlag validates --game and resolves aliases, before any command
+// that takes it starts work
+func resolveGameFlag(cmd *cobra.Command, args []string) error {
+	flag := cmd.Flags().Lookup("game")
+	if flag == nil || !flag.Changed {
+		return nil
+	}
+
+	games, err := loadGames()
+	if err != nil {
+		return err
+	}
+	game, err := server.LookupGame(games, gameType)
+	if err != nil {
+		return err
+	}
+	gameType = game.Name
+	return nil
+}
+
+// loadGames returns the registry with the definitions from GAME_DEFINITIONS_DIR
+func loadGames() ([]server.Game, error) {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	return server.Games(cfg.GetString("GAME_DEFINITIONS_DIR", gamedef.DefaultDir))
+}
+
+// gameFlagUsage is the --game help, listing the games gamekeeper knows
+// without extra definitions. It is built once for all commands.
+var gameFlagUsage = sync.OnceValue(func() string {
+	games, err := server.Games("")
+	if err != nil {
+		return fmt.Sprintf("Game (see gamekeeper games list; built-in definitions failed to load: %v)", err)
+	}
+	names := make([]string, len(games))
+	for i, g := range games {
+		names[i] = g.Name
+	}
+	return fmt.Sprintf("Game (%s)", strings.Join(names, ", "))
+})
+
+func completeGames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	games, err := loadGames()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	var names []string
+	for _, g := range games {
+		names = append(names, fmt.Sprintf("%s\t%s", g.Name, g.DisplayName))
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+func runGamesList(cmd *cobra.Command, args []string) error {
+	games, err := loadGames()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tDISPLAY NAME\tALIASES\tCAPABILITIES\tMANAGER")
+	for _, g := range games {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", g.Name, g.DisplayName, orDash(strings.Join(g.Aliases, ", ")), orDash(capabilityList(g)), managerKind(g))
+	}
+	return w.Flush()
+}
+
+func runGamesShow(cmd *cobra.Command, args []string) error {
+	games, err := loadGames()
+	if err != nil {
+		return err
+	}
+	game, err := server.LookupGame(games, args[0])
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Name:\t%s\n", game.Name)
+	fmt.Fprintf(w, "Display name:\t%s\n", game.DisplayName)
+	fmt.Fprintf(w, "Aliases:\t%s\n", orDash(strings.Join(game.Aliases, ", ")))
+	fmt.Fprintf(w, "Capabilities:\t%s\n", orDash(capabilityList(game)))
+	fmt.Fprintf(w, "Manager:\t%s\n", managerKind(game))
+
+	if def := game.Definition; def != nil {
+		fmt.Fprintf(w, "Definition:\t%s\n", def.Source)
+		fmt.Fprintf(w, "Install:\t%s\n", installSummary(def.Install))
+		if def.Start.Command != "" {
+			fmt.Fprintf(w, "Start:\t%s\n", strings.Join(append([]string{def.Start.Command}, def.Start.Args...), " "))
+		}
+		for _, t := range def.Templates {
+			fmt.Fprintf(w, "Template:\t%s -> %s (%s)\n", t.Source, t.Target, t.ConfigMode())
+		}
+		for _, p := range def.Ports {
+			line := fmt.Sprintf("%d/%s %s", p.Port, p.ProtocolName(), p.Name)
+			if p.ConfigKey != "" {
+				line += fmt.Sprintf(" (%s)", p.ConfigKey)
+			}
+			fmt.Fprintf(w, "Port:\t%s\n", line)
+		}
+		if def.Ready.LogPattern != "" {
+			fmt.Fprintf(w, "Ready when:\t%s\n", def.Ready.LogPattern)
+		}
+		if def.Console.Save != "" {
+			fmt.Fprintf(w, "Save command:\t%s\n", def.Console.Save)
+		}
+		if def.Console.Stop != "" {
+			fmt.Fprintf(w, "Stop command:\t%s\n", def.Console.Stop)
+		}
+	}
+
+	keys := make([]string, 0, len(game.Defaults))
+	for k := range game.Defaults {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(w, "Default:\t%s=%s\n", k, game.Defaults[k])
+	}
+	return w.Flush()
+}
+
+// installSummary describes a definition's install source in one line
+func installSummary(install gamedef.Install) string {
+	switch {
+	case install.Steam != nil:
+		s := fmt.Sprintf("steam app %d", install.Steam.AppID)
+		if install.Steam.Branch != "" {
+			s += fmt.Sprintf(", branch %s", install.Steam.Branch)
+		}
+		if install.Steam.WorkshopAppID != 0 {
+			s += fmt.Sprintf(", workshop app %d", install.Steam.WorkshopAppID)
+		}
+		return s
+	case install.Java != nil:
+		return "java jar from " + install.Java.URL
+	case install.URL != nil:
+		return "download from " + install.URL.URL
+	}
+	return "-"
+}
+
+func capabilityList(g server.Game) string {
+	var caps []string
+	for _, c := range gamedef.Capabilities {
+		if g.Has(c) {
+			caps = append(caps, string(c))
+		}
+	}
+	return strings.Join(caps, ", ")
+}
+
+func managerKind(g server.Game) string {
+	if g.Dedicated {
+		return "dedicated"
+	}
+	return "definition"
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
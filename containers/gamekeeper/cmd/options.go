@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/config"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/server"
+	"github.com/spf13/cobra"
+)
+
+var optionsCmd = &cobra.Command{
+	Use:   "options",
+	Short: "Document the server options a game takes from config",
+	Long: `Print the config keys that map to the game server's command-line options
+as a Markdown table. docs/ is generated from this with make docs.`,
+	RunE: runOptions,
+}
+
+func init() {
+	addGameFlag(optionsCmd)
+}
+
+func runOptions(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load("")
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	mgr, err := server.NewManager(gameType, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create server manager: %w", err)
+	}
+
+	documenter, ok := mgr.(server.OptionDocumenter)
+	if !ok {
+		return fmt.Errorf("no option table for %s", gameType)
+	}
+
+	fmt.Printf("# %s server options\n\n", gameType)
+	fmt.Println("Generated by `gamekeeper options`; edit the option table and run `make docs`.")
+	fmt.Println()
+	fmt.Print(documenter.OptionsMarkdown())
+	return nil
+}
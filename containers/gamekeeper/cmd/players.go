@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/config"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/output"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/server"
+	"github.com/spf13/cobra"
+)
+
+var playersCmd = &cobra.Command{
+	Use:   "players",
+	Short: "Manage operators, whitelist and bans",
+}
+
+var playersSyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Apply the configured player lists",
+	Long: `Write the operator, whitelist and ban lists from config and, when the
+server is running with RCON enabled, apply the changes live.`,
+	RunE: runPlayersSync,
+}
+
+func init() {
+	addGameFlag(playersSyncCmd)
+	playersSyncCmd.Flags().StringVar(&configPath, "config", "/home/kubelize/config-data/config-values.yaml", "Path to config values file")
+
+	playersCmd.AddCommand(playersSyncCmd)
+}
+
+func runPlayersSync(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	mgr, err := server.NewManager(gameType, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create server manager: %w", err)
+	}
+
+	syncer, ok := mgr.(server.PlayerListSyncer)
+	if !ok {
+		return fmt.Errorf("player lists are not supported for %s", gameType)
+	}
+
+	output.Section("Syncing player lists")
+	if err := syncer.SyncPlayerLists(true); err != nil {
+		return fmt.Errorf("player list sync failed: %w", err)
+	}
+
+	fmt.Println("✅ Player lists synced")
+	return nil
+}
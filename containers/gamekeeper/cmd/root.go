@@ -19,7 +19,8 @@ var rootCmd = &cobra.Command{
 	
 It handles installation, updates, mod management, configuration, and server startup
 for multiple game types including Hytale, Conan Exiles, Seven Days to Die, and more.`,
-	SilenceUsage: true,
+	SilenceUsage:      true,
+	PersistentPreRunE: resolveGameFlag,
 }
 
 // Execute runs the root command
@@ -40,6 +41,12 @@ func init() {
 	rootCmd.AddCommand(updateCmd)
 	rootCmd.AddCommand(validateCmd)
 	rootCmd.AddCommand(modsCmd)
+	rootCmd.AddCommand(cacheCmd)
+	rootCmd.AddCommand(bundleCmd)
+	rootCmd.AddCommand(playersCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(optionsCmd)
+	rootCmd.AddCommand(gamesCmd)
 }
 
 var versionCmd = &cobra.Command{
@@ -30,11 +30,10 @@ var startCmd = &cobra.Command{
 }
 
 func init() {
-	startCmd.Flags().StringVar(&gameType, "game", "", "Game type (hytale, conan-exiles, sdtd, palworld, valheim, minecraft)")
+	addGameFlag(startCmd)
 	startCmd.Flags().StringVar(&configPath, "config", "/home/kubelize/config-data/config-values.yaml", "Path to config values file")
 	startCmd.Flags().BoolVar(&skipUpdate, "skip-update", false, "Skip checking for game updates")
 	startCmd.Flags().BoolVar(&forceUpdate, "force-update", false, "Force update even if up to date")
-	startCmd.MarkFlagRequired("game")
 }
 
 func runStart(cmd *cobra.Command, args []string) error {
@@ -61,10 +60,14 @@ func runStart(cmd *cobra.Command, args []string) error {
 	}
 	output.Success()
 
+	// An update cut short mid-swap is rolled back before anything runs
+	if err := server.RecoverUpdate(mgr); err != nil {
+		return fmt.Errorf("setup failed: %w", err)
+	}
+
 	// Download/update phase
 	if !skipUpdate {
-		autoUpdate := cfg.GetBool("HYTALE_AUTO_UPDATE", true)
-		if autoUpdate || forceUpdate {
+		if server.AutoUpdateEnabled(cfg, gameType) || forceUpdate {
 			output.Section("Checking for game updates")
 			if err := mgr.Update(forceUpdate); err != nil {
 				output.Error(err.Error())
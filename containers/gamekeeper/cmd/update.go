@@ -4,12 +4,15 @@ import (
 	"fmt"
 
 	"github.com/kubelize/game-servers/gamekeeper/pkg/config"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/output"
 	"github.com/kubelize/game-servers/gamekeeper/pkg/server"
 	"github.com/spf13/cobra"
 )
 
 var (
-	checkOnly bool
+	checkOnly    bool
+	rollbackTo   string
+	restoreWorld bool
 )
 
 var updateCmd = &cobra.Command{
@@ -19,10 +22,10 @@ var updateCmd = &cobra.Command{
 }
 
 func init() {
-	updateCmd.Flags().StringVar(&gameType, "game", "", "Game type")
+	addGameFlag(updateCmd)
 	updateCmd.Flags().StringVar(&configPath, "config", "/home/kubelize/config-data/config-values.yaml", "Path to config values file")
 	updateCmd.Flags().BoolVar(&checkOnly, "check-only", false, "Only check for updates, don't apply")
-	updateCmd.MarkFlagRequired("game")
+	updateCmd.Flags().BoolVar(&forceUpdate, "force", false, "Reinstall even if already up to date")
 }
 
 func runUpdate(cmd *cobra.Command, args []string) error {
@@ -49,7 +52,74 @@ func runUpdate(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	return mgr.Update(true)
+	return mgr.Update(forceUpdate)
+}
+
+var updateRollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Restore a previously installed game build",
+	Long: `Restore a previously installed game build.
+
+Without --to the most recently replaced build is restored. The restored build
+is pinned so auto-update doesn't reinstall the build that was rolled back;
+run 'gamekeeper update unpin' to resume updates.`,
+	RunE: runUpdateRollback,
+}
+
+var updateUnpinCmd = &cobra.Command{
+	Use:   "unpin",
+	Short: "Allow updates to replace a rolled-back build",
+	RunE:  runUpdateUnpin,
+}
+
+func init() {
+	addGameFlag(updateRollbackCmd)
+	updateRollbackCmd.Flags().StringVar(&configPath, "config", "/home/kubelize/config-data/config-values.yaml", "Path to config values file")
+	updateRollbackCmd.Flags().StringVar(&rollbackTo, "to", "", "Version or build ID to restore (default: previous build)")
+	updateRollbackCmd.Flags().BoolVar(&restoreWorld, "restore-world", false, "Also restore the world snapshot taken before that build was replaced")
+
+	addGameFlag(updateUnpinCmd)
+	updateUnpinCmd.Flags().StringVar(&configPath, "config", "/home/kubelize/config-data/config-values.yaml", "Path to config values file")
+
+	updateCmd.AddCommand(updateRollbackCmd)
+	updateCmd.AddCommand(updateUnpinCmd)
+}
+
+func runUpdateRollback(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	mgr, err := server.NewManager(gameType, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create server manager: %w", err)
+	}
+
+	output.Section("Rolling back game build")
+	if err := mgr.Rollback(rollbackTo, restoreWorld); err != nil {
+		return fmt.Errorf("rollback failed: %w", err)
+	}
+	return nil
+}
+
+func runUpdateUnpin(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	mgr, err := server.NewManager(gameType, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create server manager: %w", err)
+	}
+
+	if err := mgr.Unpin(); err != nil {
+		return fmt.Errorf("failed to unpin: %w", err)
+	}
+
+	fmt.Println("✅ Updates resumed")
+	return nil
 }
 
 var validateCmd = &cobra.Command{
@@ -59,9 +129,8 @@ var validateCmd = &cobra.Command{
 }
 
 func init() {
-	validateCmd.Flags().StringVar(&gameType, "game", "", "Game type")
+	addGameFlag(validateCmd)
 	validateCmd.Flags().StringVar(&configPath, "config", "/home/kubelize/config-data/config-values.yaml", "Path to config values file")
-	validateCmd.MarkFlagRequired("game")
 }
 
 func runValidate(cmd *cobra.Command, args []string) error {
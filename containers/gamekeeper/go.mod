@@ -3,6 +3,7 @@ module github.com/kubelize/game-servers/gamekeeper
 go 1.23
 
 require (
+	github.com/klauspost/compress v1.18.0
 	github.com/spf13/cobra v1.8.0
 	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,233 @@
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Format identifies an archive type
+type Format int
+
+const (
+	Unknown Format = iota
+	Zip
+	Tar
+	TarGz
+	TarZst
+)
+
+// ProgressFunc reports extraction progress in bytes
+type ProgressFunc func(done, total int64)
+
+// Options controls extraction
+type Options struct {
+	// StripComponents drops this many leading path elements from each entry
+	StripComponents int
+	// Progress is called as data is extracted; may be nil
+	Progress ProgressFunc
+}
+
+// DetectFormat determines the archive format from the file name, falling back
+// to the file's magic bytes
+func DetectFormat(path string) Format {
+	name := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(name, ".zip"), strings.HasSuffix(name, ".jar"):
+		return Zip
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return TarGz
+	case strings.HasSuffix(name, ".tar.zst"), strings.HasSuffix(name, ".tzst"):
+		return TarZst
+	case strings.HasSuffix(name, ".tar"):
+		return Tar
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return Unknown
+	}
+	defer f.Close()
+
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(f, magic); err != nil {
+		return Unknown
+	}
+	switch {
+	case magic[0] == 'P' && magic[1] == 'K':
+		return Zip
+	case magic[0] == 0x1f && magic[1] == 0x8b:
+		return TarGz
+	case magic[0] == 0x28 && magic[1] == 0xb5 && magic[2] == 0x2f && magic[3] == 0xfd:
+		return TarZst
+	}
+	return Unknown
+}
+
+// IsArchive reports whether path is an extractable archive (by name)
+func IsArchive(path string) bool {
+	name := strings.ToLower(path)
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tgz", ".tar.zst", ".tzst"} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// Extract unpacks src into dest. Entries that would land outside dest, and
+// symlinks pointing outside it, are rejected.
+func Extract(src, dest string, opts Options) error {
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return err
+	}
+
+	x := &extractor{root: filepath.Clean(dest), strip: opts.StripComponents}
+
+	switch DetectFormat(src) {
+	case Zip:
+		return x.zip(src, opts.Progress)
+	case Tar, TarGz, TarZst:
+		return x.tarFile(src, opts.Progress)
+	default:
+		return fmt.Errorf("unsupported archive format: %s", filepath.Base(src))
+	}
+}
+
+// Create writes the given paths (relative to root) into dest. The format is
+// taken from dest's extension (.tar.gz or .tar.zst); missing paths are skipped.
+func Create(dest, root string, paths []string) error {
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var compressor io.WriteCloser
+	switch DetectFormat(dest) {
+	case TarGz:
+		compressor = gzip.NewWriter(f)
+	case TarZst:
+		if compressor, err = zstd.NewWriter(f); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported archive format: %s", filepath.Base(dest))
+	}
+
+	tw := tar.NewWriter(compressor)
+	for _, p := range paths {
+		if err := addTree(tw, root, p); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := compressor.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// addTree writes root/rel and everything below it to tw
+func addTree(tw *tar.Writer, root, rel string) error {
+	start := filepath.Join(root, rel)
+	if _, err := os.Lstat(start); os.IsNotExist(err) {
+		return nil
+	}
+
+	return filepath.Walk(start, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(name)
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		_, err = io.Copy(tw, in)
+		return err
+	})
+}
+
+// Latest returns the most recently modified file in dir matching pattern,
+// ignoring the excluded base names. Files with the same modification time are
+// ordered by the version number in their name.
+func Latest(dir, pattern string, exclude ...string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, pattern))
+	if err != nil {
+		return "", err
+	}
+
+	type candidate struct {
+		path    string
+		modTime int64
+	}
+	var candidates []candidate
+	for _, m := range matches {
+		if contains(exclude, filepath.Base(m)) {
+			continue
+		}
+		info, err := os.Stat(m)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		candidates = append(candidates, candidate{m, info.ModTime().UnixNano()})
+	}
+
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("no file matching %s found in %s", pattern, dir)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].modTime != candidates[j].modTime {
+			return candidates[i].modTime > candidates[j].modTime
+		}
+		return CompareVersions(filepath.Base(candidates[i].path), filepath.Base(candidates[j].path)) > 0
+	})
+	return candidates[0].path, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
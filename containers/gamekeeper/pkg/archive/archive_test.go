@@ -0,0 +1,222 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// entry is a file, directory or link written into a test archive
+type entry struct {
+	name string
+	body string
+	// link makes the entry a symlink (or a hard link, for tar with hard set)
+	link string
+	hard bool
+	dir  bool
+}
+
+func writeTar(t *testing.T, path string, entries []entry) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	tw := tar.NewWriter(f)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0644, Typeflag: tar.TypeReg, Size: int64(len(e.body))}
+		switch {
+		case e.dir:
+			hdr.Typeflag, hdr.Mode, hdr.Size = tar.TypeDir, 0755, 0
+		case e.link != "" && e.hard:
+			hdr.Typeflag, hdr.Linkname, hdr.Size = tar.TypeLink, e.link, 0
+		case e.link != "":
+			hdr.Typeflag, hdr.Linkname, hdr.Size = tar.TypeSymlink, e.link, 0
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Size > 0 {
+			if _, err := tw.Write([]byte(e.body)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeZip(t *testing.T, path string, entries []entry) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for _, e := range entries {
+		hdr := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		body := e.body
+		switch {
+		case e.dir:
+			hdr.Name = strings.TrimSuffix(e.name, "/") + "/"
+			hdr.SetMode(os.ModeDir | 0755)
+		case e.link != "":
+			hdr.SetMode(os.ModeSymlink | 0777)
+			body = e.link
+		default:
+			hdr.SetMode(0644)
+		}
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		entries []entry
+		strip   int
+		// outsideLink is a symlink to a directory outside dest, placed in
+		// dest before extraction
+		outsideLink string
+		wantErr     bool
+		// want maps paths below dest to their expected content
+		want map[string]string
+	}{
+		{
+			name:    "tar files and directories",
+			format:  "tar",
+			entries: []entry{{name: "bin", dir: true}, {name: "bin/run.sh", body: "run"}, {name: "server.jar", body: "jar"}},
+			want:    map[string]string{"bin/run.sh": "run", "server.jar": "jar"},
+		},
+		{
+			name:    "zip files and directories",
+			format:  "zip",
+			entries: []entry{{name: "Server", dir: true}, {name: "Server/HytaleServer.jar", body: "jar"}, {name: "Assets.zip", body: "assets"}},
+			want:    map[string]string{"Server/HytaleServer.jar": "jar", "Assets.zip": "assets"},
+		},
+		{
+			name:    "strip components",
+			format:  "tar",
+			entries: []entry{{name: "server-1.0/", dir: true}, {name: "server-1.0/bin/run.sh", body: "run"}},
+			strip:   1,
+			want:    map[string]string{"bin/run.sh": "run"},
+		},
+		{
+			name:    "symlink inside the destination",
+			format:  "tar",
+			entries: []entry{{name: "lib/real.so", body: "so"}, {name: "lib/link.so", link: "real.so"}},
+			want:    map[string]string{"lib/link.so": "so"},
+		},
+		{name: "tar parent traversal", format: "tar", entries: []entry{{name: "../evil", body: "x"}}, wantErr: true},
+		{name: "tar nested traversal", format: "tar", entries: []entry{{name: "a/../../evil", body: "x"}}, wantErr: true},
+		{name: "tar absolute path", format: "tar", entries: []entry{{name: "/tmp/evil", body: "x"}}, wantErr: true},
+		{name: "zip parent traversal", format: "zip", entries: []entry{{name: "../evil", body: "x"}}, wantErr: true},
+		{name: "tar symlink out of the destination", format: "tar", entries: []entry{{name: "link", link: "../outside"}}, wantErr: true},
+		{name: "tar absolute symlink", format: "tar", entries: []entry{{name: "link", link: "/etc"}}, wantErr: true},
+		{name: "zip symlink out of the destination", format: "zip", entries: []entry{{name: "link", link: "../../outside"}}, wantErr: true},
+		{
+			name:    "tar symlink then write through it",
+			format:  "tar",
+			entries: []entry{{name: "dir", link: "."}, {name: "dir/../../evil", body: "x"}},
+			wantErr: true,
+		},
+		{name: "tar hard link out of the destination", format: "tar", entries: []entry{{name: "link", link: "../outside", hard: true}}, wantErr: true},
+		{
+			name:        "write through an existing symlink",
+			format:      "tar",
+			entries:     []entry{{name: "escape/evil", body: "x"}},
+			outsideLink: "escape",
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			dest := filepath.Join(root, "dest")
+			outside := filepath.Join(root, "outside")
+			for _, dir := range []string{dest, outside} {
+				if err := os.MkdirAll(dir, 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.outsideLink != "" {
+				if err := os.Symlink(outside, filepath.Join(dest, tt.outsideLink)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			src := filepath.Join(root, "archive."+tt.format)
+			if tt.format == "zip" {
+				writeZip(t, src, tt.entries)
+			} else {
+				writeTar(t, src, tt.entries)
+			}
+
+			err := Extract(src, dest, Options{StripComponents: tt.strip})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Extract error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			// Nothing may ever land outside dest
+			for _, dir := range []string{root, outside} {
+				entries, _ := os.ReadDir(dir)
+				for _, e := range entries {
+					if name := e.Name(); name != "dest" && name != "outside" && !strings.HasPrefix(name, "archive.") {
+						t.Errorf("%s was written outside the destination", filepath.Join(dir, name))
+					}
+				}
+			}
+			if _, err := os.Lstat(filepath.Join(root, "evil")); err == nil {
+				t.Error("evil was written outside the destination")
+			}
+
+			for path, want := range tt.want {
+				got, err := os.ReadFile(filepath.Join(dest, path))
+				if err != nil {
+					t.Errorf("%s: %v", path, err)
+					continue
+				}
+				if string(got) != want {
+					t.Errorf("%s = %q, want %q", path, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.10", "1.9", 1},
+		{"1.9", "1.10", -1},
+		{"1.2", "1.2", 0},
+		{"server-1.10.zip", "server-1.9.zip", 1},
+		{"2026.01.24-6e2d4fc36", "2026.01.13-50e69c385", 1},
+		{"1.2", "1.2.1", -1},
+	}
+	for _, tt := range tests {
+		got := CompareVersions(tt.a, tt.b)
+		if (got > 0) != (tt.want > 0) || (got < 0) != (tt.want < 0) {
+			t.Errorf("CompareVersions(%q, %q) = %d, want sign of %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
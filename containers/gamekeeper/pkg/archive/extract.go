@@ -0,0 +1,324 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// extractor writes archive entries below root
+type extractor struct {
+	root  string
+	strip int
+}
+
+// zip extracts a zip archive; progress counts uncompressed bytes
+func (x *extractor) zip(src string, progress ProgressFunc) error {
+	r, err := zip.OpenReader(src)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", filepath.Base(src), err)
+	}
+	defer r.Close()
+
+	var total int64
+	for _, f := range r.File {
+		total += int64(f.UncompressedSize64)
+	}
+	counter := &counter{total: total, progress: progress}
+
+	for _, f := range r.File {
+		if err := x.zipEntry(f, counter); err != nil {
+			return err
+		}
+	}
+	counter.finish()
+	return nil
+}
+
+func (x *extractor) zipEntry(f *zip.File, counter *counter) error {
+	target, err := x.target(f.Name)
+	if err != nil || target == "" {
+		return err
+	}
+
+	mode := f.Mode()
+	switch {
+	case mode.IsDir():
+		return os.MkdirAll(target, dirMode(mode))
+	case mode&os.ModeSymlink != 0:
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		link, err := io.ReadAll(io.LimitReader(rc, 4096))
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		return x.symlink(string(link), target)
+	default:
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		return x.writeFile(target, rc, fileMode(mode), f.Modified, counter)
+	}
+}
+
+// tarFile extracts a (compressed) tarball; progress counts archive bytes read
+func (x *extractor) tarFile(src string, progress ProgressFunc) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	counter := &counter{total: info.Size(), progress: progress}
+	in := io.TeeReader(f, counter)
+
+	var r io.Reader
+	switch DetectFormat(src) {
+	case TarGz:
+		gz, err := gzip.NewReader(in)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", filepath.Base(src), err)
+		}
+		defer gz.Close()
+		r = gz
+	case TarZst:
+		zr, err := zstd.NewReader(in)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", filepath.Base(src), err)
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		r = in
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", filepath.Base(src), err)
+		}
+		if err := x.tarEntry(tr, hdr); err != nil {
+			return err
+		}
+	}
+	counter.finish()
+	return nil
+}
+
+func (x *extractor) tarEntry(tr *tar.Reader, hdr *tar.Header) error {
+	target, err := x.target(hdr.Name)
+	if err != nil || target == "" {
+		return err
+	}
+
+	mode := hdr.FileInfo().Mode()
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		return os.MkdirAll(target, dirMode(mode))
+	case tar.TypeSymlink:
+		return x.symlink(hdr.Linkname, target)
+	case tar.TypeLink:
+		source, err := x.target(hdr.Linkname)
+		if err != nil || source == "" {
+			return fmt.Errorf("illegal link in archive: %s -> %s", hdr.Name, hdr.Linkname)
+		}
+		if err := x.clear(target); err != nil {
+			return err
+		}
+		return os.Link(source, target)
+	case tar.TypeReg:
+		return x.writeFile(target, tr, fileMode(mode), hdr.ModTime, nil)
+	}
+	// Devices, fifos and other special files are skipped
+	return nil
+}
+
+// target maps an entry name to a path below root, rejecting path traversal.
+// An empty target means the entry was stripped away entirely.
+func (x *extractor) target(name string) (string, error) {
+	name = filepath.ToSlash(name)
+	if strings.HasPrefix(name, "/") || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("illegal path in archive: %s", name)
+	}
+
+	parts := strings.Split(strings.Trim(name, "/"), "/")
+	for _, p := range parts {
+		if p == ".." {
+			return "", fmt.Errorf("illegal path in archive: %s", name)
+		}
+	}
+	if len(parts) <= x.strip {
+		return "", nil
+	}
+	parts = parts[x.strip:]
+
+	target := filepath.Join(x.root, filepath.FromSlash(strings.Join(parts, "/")))
+	if !within(x.root, target) {
+		return "", fmt.Errorf("illegal path in archive: %s", name)
+	}
+	if target == x.root {
+		return "", nil
+	}
+
+	// Never write through a symlink the archive (or anyone else) placed earlier
+	if err := x.checkParents(target); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+// checkParents rejects targets whose parent directories resolve outside root
+func (x *extractor) checkParents(target string) error {
+	dir := filepath.Dir(target)
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	root, err := filepath.EvalSymlinks(x.root)
+	if err != nil {
+		return err
+	}
+	if !within(root, resolved) {
+		return fmt.Errorf("illegal path in archive: %s escapes the destination", target)
+	}
+	return nil
+}
+
+// symlink creates a link whose target must stay inside root
+func (x *extractor) symlink(link, target string) error {
+	resolved := link
+	if !filepath.IsAbs(link) {
+		resolved = filepath.Join(filepath.Dir(target), link)
+	}
+	if filepath.IsAbs(link) || !within(x.root, resolved) {
+		return fmt.Errorf("illegal symlink in archive: %s -> %s", target, link)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if err := x.clear(target); err != nil {
+		return err
+	}
+	return os.Symlink(link, target)
+}
+
+// writeFile writes r to target with the given permissions and modification time
+func (x *extractor) writeFile(target string, r io.Reader, mode os.FileMode, modTime time.Time, counter *counter) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if err := x.clear(target); err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+
+	w := io.Writer(out)
+	if counter != nil {
+		w = io.MultiWriter(out, counter)
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to extract %s: %w", target, err)
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	// The umask may have dropped bits; apply the archived permissions exactly
+	if err := os.Chmod(target, mode); err != nil {
+		return err
+	}
+	if !modTime.IsZero() {
+		os.Chtimes(target, modTime, modTime)
+	}
+	return nil
+}
+
+// clear removes an existing symlink or file at target so it is replaced, not written through
+func (x *extractor) clear(target string) error {
+	info, err := os.Lstat(target)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("cannot replace directory %s with a file", target)
+	}
+	return os.Remove(target)
+}
+
+// within reports whether path is root or below it
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator)))
+}
+
+func fileMode(mode os.FileMode) os.FileMode {
+	if mode.Perm() == 0 {
+		return 0644
+	}
+	return mode.Perm()
+}
+
+func dirMode(mode os.FileMode) os.FileMode {
+	if mode.Perm() == 0 {
+		return 0755
+	}
+	return mode.Perm() | 0700
+}
+
+// counter reports progress as bytes flow through it
+type counter struct {
+	done     int64
+	total    int64
+	progress ProgressFunc
+}
+
+func (c *counter) Write(p []byte) (int, error) {
+	c.done += int64(len(p))
+	if c.progress != nil {
+		c.progress(c.done, c.total)
+	}
+	return len(p), nil
+}
+
+// finish reports completion, since compressed trailers may not be read to the end
+func (c *counter) finish() {
+	if c.progress != nil {
+		c.progress(c.total, c.total)
+	}
+}
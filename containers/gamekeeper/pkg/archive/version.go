@@ -0,0 +1,46 @@
+package archive
+
+import (
+	"strconv"
+	"strings"
+)
+
+// CompareVersions compares two strings by their numeric and text runs, so
+// "server-1.10.zip" sorts after "server-1.9.zip"
+func CompareVersions(a, b string) int {
+	ra, rb := splitRuns(a), splitRuns(b)
+	for i := 0; i < len(ra) && i < len(rb); i++ {
+		na, errA := strconv.ParseUint(ra[i], 10, 64)
+		nb, errB := strconv.ParseUint(rb[i], 10, 64)
+		if errA == nil && errB == nil {
+			if na != nb {
+				if na < nb {
+					return -1
+				}
+				return 1
+			}
+			continue
+		}
+		if c := strings.Compare(ra[i], rb[i]); c != 0 {
+			return c
+		}
+	}
+	return len(ra) - len(rb)
+}
+
+// splitRuns splits s into alternating digit and non-digit runs
+func splitRuns(s string) []string {
+	var runs []string
+	start := 0
+	for i := 1; i <= len(s); i++ {
+		if i == len(s) || isDigit(s[i]) != isDigit(s[start]) {
+			runs = append(runs, s[start:i])
+			start = i
+		}
+	}
+	return runs
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,228 @@
+package cache
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/config"
+)
+
+// Cache is a content-addressed artifact store. Files live under
+// <dir>/sha256/<aa>/<digest>; other digests (sha1, md5, sha512) and named keys
+// such as "hytale-server/<version>" are small index files pointing at the
+// sha256 entry. Every write is a rename, so one directory can be shared by
+// several pods on a ReadWriteMany volume.
+type Cache struct {
+	dir string
+}
+
+var digestPattern = regexp.MustCompile(`^[0-9a-f]{32,128}$`)
+
+// New returns the cache in ARTIFACT_CACHE_DIR, or nil when caching is disabled
+func New(cfg *config.Config) *Cache {
+	dir := cfg.GetString("ARTIFACT_CACHE_DIR", "")
+	if dir == "" {
+		return nil
+	}
+	return Open(dir)
+}
+
+// Open returns the cache rooted at dir
+func Open(dir string) *Cache {
+	return &Cache{dir: dir}
+}
+
+// Dir returns the cache root
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Path returns the stored file for a digest, if present
+func (c *Cache) Path(algo, digest string) (string, bool) {
+	algo, digest = strings.ToLower(algo), strings.ToLower(digest)
+	if !digestPattern.MatchString(digest) || newHash(algo) == nil {
+		return "", false
+	}
+
+	if algo != "sha256" {
+		return c.resolveIndex(c.indexPath(algo, digest))
+	}
+
+	p := c.objectPath(digest)
+	if _, err := os.Stat(p); err != nil {
+		return "", false
+	}
+	return p, true
+}
+
+// KeyPath returns the stored file for a named key, if present
+func (c *Cache) KeyPath(key string) (string, bool) {
+	return c.resolveIndex(c.keyPath(key))
+}
+
+// resolveIndex follows an index file to its sha256 entry
+func (c *Cache) resolveIndex(path string) (string, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	return c.Path("sha256", strings.TrimSpace(string(data)))
+}
+
+// Store copies file into the cache and indexes it under its sha1, md5 and
+// sha512 digests and the given keys. It returns the sha256 digest.
+func (c *Cache) Store(file string, keys ...string) (string, error) {
+	in, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(c.dir, ".incoming-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	hashes := map[string]hash.Hash{}
+	writers := []io.Writer{tmp}
+	for _, algo := range []string{"sha256", "sha1", "md5", "sha512"} {
+		hashes[algo] = newHash(algo)
+		writers = append(writers, hashes[algo])
+	}
+	if _, err := io.Copy(io.MultiWriter(writers...), in); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	os.Chmod(tmp.Name(), 0644)
+
+	digest := hex.EncodeToString(hashes["sha256"].Sum(nil))
+	object := c.objectPath(digest)
+	if err := os.MkdirAll(filepath.Dir(object), 0755); err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(object); err != nil {
+		if err := os.Rename(tmp.Name(), object); err != nil {
+			return "", err
+		}
+	}
+
+	for algo, h := range hashes {
+		if algo == "sha256" {
+			continue
+		}
+		if err := c.writeIndex(c.indexPath(algo, hex.EncodeToString(h.Sum(nil))), digest); err != nil {
+			return "", err
+		}
+	}
+	for _, key := range keys {
+		if err := c.writeIndex(c.keyPath(key), digest); err != nil {
+			return "", err
+		}
+	}
+	return digest, nil
+}
+
+// CopyTo copies a cached file to dest atomically. Copies (not links) keep
+// the cache safe from tools that modify files in place.
+func CopyTo(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	tmp := dest + ".part"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dest)
+}
+
+// KeyDigest returns the index name used for a key
+func KeyDigest(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *Cache) objectPath(digest string) string {
+	return filepath.Join(c.dir, "sha256", digest[:2], digest)
+}
+
+func (c *Cache) indexPath(algo, digest string) string {
+	return filepath.Join(c.dir, algo, digest[:2], digest)
+}
+
+func (c *Cache) keyPath(key string) string {
+	d := KeyDigest(key)
+	return filepath.Join(c.dir, "keys", d[:2], d)
+}
+
+// writeIndex atomically writes an index file pointing at a sha256 digest
+func (c *Cache) writeIndex(path, digest string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".index-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.WriteString(digest + "\n"); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	os.Chmod(tmp.Name(), 0644)
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to index %s: %w", digest, err)
+	}
+	return nil
+}
+
+func newHash(algo string) hash.Hash {
+	switch algo {
+	case "sha256":
+		return sha256.New()
+	case "sha512":
+		return sha512.New()
+	case "sha1":
+		return sha1.New()
+	case "md5":
+		return md5.New()
+	}
+	return nil
+}
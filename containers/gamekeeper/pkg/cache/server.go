@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// Handler serves the cache as a mirror for other pods:
+//
+//	GET /<algo>/<digest>   file by sha256, sha1, md5 or sha512 digest
+//	GET /keys/<key-digest> file by named key (see KeyDigest)
+//	GET /healthz
+func (c *Cache) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok\n"))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		if len(parts) != 2 {
+			http.NotFound(w, r)
+			return
+		}
+
+		var path string
+		var ok bool
+		if parts[0] == "keys" {
+			path, ok = c.keyDigestPath(parts[1])
+		} else {
+			path, ok = c.Path(parts[0], parts[1])
+		}
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		// ServeFile handles Range requests, so mirror downloads can resume too
+		w.Header().Set("Content-Type", "application/octet-stream")
+		http.ServeFile(w, r, path)
+	})
+	return mux
+}
+
+// keyDigestPath resolves an already-hashed key
+func (c *Cache) keyDigestPath(d string) (string, bool) {
+	d = strings.ToLower(d)
+	if len(d) != 64 || !digestPattern.MatchString(d) {
+		return "", false
+	}
+	return c.resolveIndex(filepath.Join(c.dir, "keys", d[:2], d))
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -149,3 +150,18 @@ func (c *Config) Get(key string) interface{} {
 func (c *Config) Set(key string, value interface{}) {
 	c.values[key] = value
 }
+
+// Values returns all config values with environment variables applied on top,
+// as the Get methods see them
+func (c *Config) Values() map[string]interface{} {
+	values := make(map[string]interface{}, len(c.values))
+	for k, v := range c.values {
+		values[k] = v
+	}
+	for _, env := range os.Environ() {
+		if k, v, ok := strings.Cut(env, "="); ok && v != "" {
+			values[k] = v
+		}
+	}
+	return values
+}
@@ -0,0 +1,148 @@
+package configfile
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Mode decides who owns a rendered config file
+type Mode string
+
+const (
+	// SeedOnce renders the file only when it doesn't exist yet
+	SeedOnce Mode = "seed-once"
+	// AlwaysOverwrite replaces the file on every render
+	AlwaysOverwrite Mode = "always-overwrite"
+	// ManagedKeys merges config changes into the file, only for the keys
+	// the template renders, keeping edits made in-game
+	ManagedKeys Mode = "managed-keys"
+)
+
+// ParseMode validates a mode name from config
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(strings.ToLower(strings.TrimSpace(s))); m {
+	case SeedOnce, AlwaysOverwrite, ManagedKeys:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown config file mode %q (want seed-once, always-overwrite or managed-keys)", s)
+}
+
+// File is a game config file rendered from config
+type File struct {
+	Path string
+	Mode Mode
+	// Format is json, xml, ini or properties; empty detects it from the extension
+	Format string
+	// StateDir keeps the last rendered output, the base of managed-keys merges
+	StateDir string
+	// Perm is used when the file is created; existing files keep theirs
+	Perm os.FileMode
+}
+
+// Write applies freshly rendered content to the file according to its mode
+// and describes what it did
+func (f File) Write(rendered []byte) (string, error) {
+	current, err := os.ReadFile(f.Path)
+	exists := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	var result []byte
+	var summary string
+	switch {
+	case !exists:
+		result, summary = rendered, "created"
+	case f.Mode == SeedOnce:
+		return "already exists (seed-once)", nil
+	case f.Mode == AlwaysOverwrite:
+		result, summary = rendered, "overwritten"
+	case f.Mode == ManagedKeys:
+		base, err := os.ReadFile(f.basePath())
+		if err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+		var changed int
+		result, changed, err = Merge(f.format(), base, current, rendered)
+		if err != nil {
+			return "", fmt.Errorf("failed to merge %s: %w", filepath.Base(f.Path), err)
+		}
+		summary = fmt.Sprintf("merged %d key(s) from config", changed)
+		if changed == 0 {
+			summary = "up to date"
+		}
+	default:
+		return "", fmt.Errorf("unknown config file mode %q", f.Mode)
+	}
+
+	if !exists || !bytes.Equal(result, current) {
+		if err := f.write(result); err != nil {
+			return "", err
+		}
+	}
+	if err := f.saveBase(rendered); err != nil {
+		return "", err
+	}
+	return summary, nil
+}
+
+// format returns the configured or detected file format
+func (f File) format() string {
+	if f.Format != "" {
+		return f.Format
+	}
+	switch strings.ToLower(filepath.Ext(f.Path)) {
+	case ".json":
+		return "json"
+	case ".xml":
+		return "xml"
+	case ".properties":
+		return "properties"
+	}
+	return "ini"
+}
+
+// basePath is where the last rendered output is kept. Files are told apart
+// by their full path, so same-named files in different directories each
+// have their own base.
+func (f File) basePath() string {
+	path, err := filepath.Abs(f.Path)
+	if err != nil {
+		path = filepath.Clean(f.Path)
+	}
+	sum := sha256.Sum256([]byte(path))
+	return filepath.Join(f.StateDir, "rendered", hex.EncodeToString(sum[:6])+"-"+filepath.Base(f.Path))
+}
+
+func (f File) saveBase(rendered []byte) error {
+	if err := os.MkdirAll(filepath.Dir(f.basePath()), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(f.basePath(), rendered, 0600)
+}
+
+// write replaces the file atomically, keeping the permissions of an existing one
+func (f File) write(data []byte) error {
+	perm := f.Perm
+	if perm == 0 {
+		perm = 0644
+	}
+	if info, err := os.Stat(f.Path); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	tmp := f.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(f.Path), err)
+	}
+	if err := os.Rename(tmp, f.Path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(f.Path), err)
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package configfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// keySep joins the path of a nested JSON key; it can't appear in real keys
+const keySep = "\x1f"
+
+// jsonObject is a JSON object that keeps its key order. Values are either
+// nested objects or compacted raw JSON leaves (scalars, arrays, empty objects).
+type jsonObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// jsonDoc addresses leaves by their key path
+type jsonDoc struct {
+	root *jsonObject
+}
+
+func parseJSON(data []byte) (*jsonDoc, error) {
+	root, err := decodeObject(bytes.TrimSpace(data))
+	if err != nil {
+		return nil, err
+	}
+	return &jsonDoc{root: root}, nil
+}
+
+func decodeObject(data []byte) (*jsonObject, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("expected a JSON object")
+	}
+
+	obj := &jsonObject{values: make(map[string]interface{})}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key := tok.(string)
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+		value, err := decodeValue(raw)
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := obj.values[key]; !dup {
+			obj.keys = append(obj.keys, key)
+		}
+		obj.values[key] = value
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// decodeValue returns a nested object for non-empty objects, otherwise the compacted leaf
+func decodeValue(raw json.RawMessage) (interface{}, error) {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, raw); err != nil {
+		return nil, err
+	}
+	if compact.Len() > 2 && compact.Bytes()[0] == '{' {
+		return decodeObject(compact.Bytes())
+	}
+	return json.RawMessage(compact.Bytes()), nil
+}
+
+// Keys lists the leaves. Empty objects are left out: in templates they are
+// placeholders the game fills in (like "Mods": {}), not values to enforce.
+func (d *jsonDoc) Keys() []string {
+	var keys []string
+	var walk func(prefix string, obj *jsonObject)
+	walk = func(prefix string, obj *jsonObject) {
+		for _, k := range obj.keys {
+			switch v := obj.values[k].(type) {
+			case *jsonObject:
+				walk(prefix+k+keySep, v)
+			case json.RawMessage:
+				if string(v) != "{}" {
+					keys = append(keys, prefix+k)
+				}
+			}
+		}
+	}
+	walk("", d.root)
+	return keys
+}
+
+func (d *jsonDoc) Get(key string) (string, bool) {
+	path := strings.Split(key, keySep)
+	obj := d.root
+	for _, k := range path[:len(path)-1] {
+		child, ok := obj.values[k].(*jsonObject)
+		if !ok {
+			return "", false
+		}
+		obj = child
+	}
+	raw, ok := obj.values[path[len(path)-1]].(json.RawMessage)
+	return string(raw), ok
+}
+
+func (d *jsonDoc) Set(key, value string) {
+	path := strings.Split(key, keySep)
+	obj := d.root
+	for _, k := range path[:len(path)-1] {
+		child, ok := obj.values[k].(*jsonObject)
+		if !ok {
+			// Missing, or a leaf where config now has an object
+			child = &jsonObject{values: make(map[string]interface{})}
+			obj.set(k, child)
+		}
+		obj = child
+	}
+	obj.set(path[len(path)-1], json.RawMessage(value))
+}
+
+func (d *jsonDoc) Delete(key string) {
+	path := strings.Split(key, keySep)
+	obj := d.root
+	for _, k := range path[:len(path)-1] {
+		child, ok := obj.values[k].(*jsonObject)
+		if !ok {
+			return
+		}
+		obj = child
+	}
+	last := path[len(path)-1]
+	if _, ok := obj.values[last]; !ok {
+		return
+	}
+	delete(obj.values, last)
+	for i, k := range obj.keys {
+		if k == last {
+			obj.keys = append(obj.keys[:i], obj.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+func (d *jsonDoc) Bytes() ([]byte, error) {
+	var compact bytes.Buffer
+	if err := d.root.encode(&compact); err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, compact.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
+
+func (o *jsonObject) set(key string, value interface{}) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+func (o *jsonObject) encode(buf *bytes.Buffer) error {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+
+	buf.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := enc.Encode(k); err != nil {
+			return err
+		}
+		buf.Truncate(buf.Len() - 1) // Encode adds a newline
+		buf.WriteByte(':')
+		switch v := o.values[k].(type) {
+		case *jsonObject:
+			if err := v.encode(buf); err != nil {
+				return err
+			}
+		case json.RawMessage:
+			buf.Write(v)
+		}
+	}
+	buf.WriteByte('}')
+	return nil
+}
@@ -0,0 +1,161 @@
+package configfile
+
+import (
+	"bytes"
+	"strings"
+)
+
+// linesDoc is a line-based key=value file: INI with [sections], or Java
+// properties. Edits touch only the lines of the keys involved.
+type linesDoc struct {
+	lines    []string
+	sections bool
+}
+
+// lineEntry locates a setting in the file
+type lineEntry struct {
+	key    string
+	index  int
+	prefix string // the line up to the value, e.g. "Key = "
+	value  string
+}
+
+func parseLines(data []byte, sections bool) *linesDoc {
+	text := strings.TrimSuffix(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	doc := &linesDoc{sections: sections}
+	if text != "" {
+		doc.lines = strings.Split(text, "\n")
+	}
+	return doc
+}
+
+// entries scans the file; keys of INI files are prefixed with their section
+func (d *linesDoc) entries() []lineEntry {
+	var entries []lineEntry
+	section := ""
+	for i, line := range d.lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, ";") || strings.HasPrefix(trimmed, "!") {
+			continue
+		}
+		if d.sections && strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			section = strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+			continue
+		}
+
+		sep := strings.IndexAny(line, d.separators())
+		if sep < 0 {
+			continue
+		}
+		key := strings.TrimSpace(line[:sep])
+		valueStart := sep + 1
+		for valueStart < len(line) && (line[valueStart] == ' ' || line[valueStart] == '\t') {
+			valueStart++
+		}
+		if d.sections {
+			key = section + keySep + key
+		}
+		entries = append(entries, lineEntry{
+			key:    key,
+			index:  i,
+			prefix: line[:valueStart],
+			value:  strings.TrimRight(line[valueStart:], " \t"),
+		})
+	}
+	return entries
+}
+
+func (d *linesDoc) separators() string {
+	if d.sections {
+		return "="
+	}
+	return "=:"
+}
+
+func (d *linesDoc) find(key string) (lineEntry, bool) {
+	for _, e := range d.entries() {
+		if e.key == key {
+			return e, true
+		}
+	}
+	return lineEntry{}, false
+}
+
+func (d *linesDoc) Keys() []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, e := range d.entries() {
+		if !seen[e.key] {
+			seen[e.key] = true
+			keys = append(keys, e.key)
+		}
+	}
+	return keys
+}
+
+func (d *linesDoc) Get(key string) (string, bool) {
+	e, ok := d.find(key)
+	return e.value, ok
+}
+
+func (d *linesDoc) Set(key, value string) {
+	if e, ok := d.find(key); ok {
+		d.lines[e.index] = e.prefix + value
+		return
+	}
+
+	name := key
+	section := ""
+	if d.sections {
+		section, name, _ = strings.Cut(key, keySep)
+	}
+	line := name + "=" + value
+	if !d.sections {
+		d.lines = append(d.lines, line)
+		return
+	}
+
+	// After the last line of the section, or in a new section at the end.
+	// Keys outside any section go before the first header.
+	insert := -1
+	if section == "" {
+		insert = 0
+	}
+	current := ""
+	for i, l := range d.lines {
+		trimmed := strings.TrimSpace(l)
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			current = strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+			if current == section {
+				insert = i + 1
+			}
+			continue
+		}
+		if current == section && trimmed != "" {
+			insert = i + 1
+		}
+	}
+	if insert < 0 {
+		if len(d.lines) > 0 {
+			d.lines = append(d.lines, "")
+		}
+		d.lines = append(d.lines, "["+section+"]", line)
+		return
+	}
+	d.lines = append(d.lines[:insert], append([]string{line}, d.lines[insert:]...)...)
+}
+
+func (d *linesDoc) Delete(key string) {
+	if e, ok := d.find(key); ok {
+		d.lines = append(d.lines[:e.index], d.lines[e.index+1:]...)
+	}
+}
+
+func (d *linesDoc) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	for _, l := range d.lines {
+		buf.WriteString(l)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
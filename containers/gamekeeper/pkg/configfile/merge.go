@@ -0,0 +1,134 @@
+package configfile
+
+import (
+	"fmt"
+	"sort"
+)
+
+// document is a parsed config file whose settings can be edited in place,
+// keeping the layout and comments of everything else
+type document interface {
+	// Keys lists the settings in file order
+	Keys() []string
+	// Get returns a setting's value in its file encoding
+	Get(key string) (string, bool)
+	Set(key, value string)
+	Delete(key string)
+	Bytes() ([]byte, error)
+}
+
+// parse reads a document in the given format
+func parse(format string, data []byte) (document, error) {
+	switch format {
+	case "json":
+		return parseJSON(data)
+	case "xml":
+		return parseXML(data), nil
+	case "ini":
+		return parseLines(data, true), nil
+	case "properties":
+		return parseLines(data, false), nil
+	}
+	return nil, fmt.Errorf("unsupported config format %q", format)
+}
+
+// Merge three-way merges rendered into current, with base being the previous
+// render. Config owns the keys the template renders: a key is written when
+// its rendered value changed since base (or is new), so values edited in the
+// file survive until config changes them. Keys config stopped rendering are
+// removed if still at their old rendered value. Without a base (the first
+// managed render of an existing file) only keys missing from the file are
+// added, since nothing tells config values from in-game edits yet. Returns
+// the merged file and how many keys changed.
+func Merge(format string, base, current, rendered []byte) ([]byte, int, error) {
+	cur, err := parse(format, current)
+	if err != nil {
+		return nil, 0, fmt.Errorf("current file: %w", err)
+	}
+	next, err := parse(format, rendered)
+	if err != nil {
+		return nil, 0, fmt.Errorf("rendered file: %w", err)
+	}
+	var prev document
+	if base != nil {
+		if prev, err = parse(format, base); err != nil {
+			// A base we can't read is no base at all
+			prev = nil
+		}
+	}
+
+	changed := 0
+	for _, key := range next.Keys() {
+		value, _ := next.Get(key)
+		existing, exists := cur.Get(key)
+		if prev == nil && exists {
+			continue
+		}
+		if prev != nil {
+			if old, ok := prev.Get(key); ok && old == value {
+				continue
+			}
+		}
+		if exists && existing == value {
+			continue
+		}
+		cur.Set(key, value)
+		changed++
+	}
+
+	if prev != nil {
+		for _, key := range prev.Keys() {
+			if _, ok := next.Get(key); ok {
+				continue
+			}
+			old, _ := prev.Get(key)
+			if existing, ok := cur.Get(key); ok && existing == old {
+				cur.Delete(key)
+				changed++
+			}
+		}
+	}
+
+	if changed == 0 {
+		return current, 0, nil
+	}
+	out, err := cur.Bytes()
+	return out, changed, err
+}
+
+// Keys lists the settings of a config file in file order
+func Keys(format string, data []byte) ([]string, error) {
+	doc, err := parse(format, data)
+	if err != nil {
+		return nil, err
+	}
+	return doc.Keys(), nil
+}
+
+// Get returns a setting of a config file in its file encoding
+func Get(format string, data []byte, key string) (string, bool, error) {
+	doc, err := parse(format, data)
+	if err != nil {
+		return "", false, err
+	}
+	value, ok := doc.Get(key)
+	return value, ok, nil
+}
+
+// Set writes settings into a config file, keeping its layout and comments.
+// Values are in the file encoding.
+func Set(format string, data []byte, values map[string]string) ([]byte, error) {
+	doc, err := parse(format, data)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		doc.Set(k, values[k])
+	}
+	return doc.Bytes()
+}
@@ -0,0 +1,169 @@
+package configfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name                    string
+		format                  string
+		base, current, rendered string
+		want                    string
+		changed                 int
+	}{
+		{
+			name:     "json config change applied, in-game edit kept",
+			format:   "json",
+			base:     `{"Name": "a", "Max": 10, "Nested": {"World": "w"}}`,
+			current:  `{"Name": "a", "Max": 50, "Nested": {"World": "w"}, "Mods": {"x": 1}}`,
+			rendered: `{"Name": "b", "Max": 10, "Nested": {"World": "w"}}`,
+			want:     "{\n  \"Name\": \"b\",\n  \"Max\": 50,\n  \"Nested\": {\n    \"World\": \"w\"\n  },\n  \"Mods\": {\n    \"x\": 1\n  }\n}\n",
+			changed:  1,
+		},
+		{
+			name:     "json nested key added and dropped key removed",
+			format:   "json",
+			base:     `{"Name": "a", "Old": true}`,
+			current:  `{"Name": "a", "Old": true}`,
+			rendered: `{"Name": "a", "Nested": {"World": "w"}}`,
+			want:     "{\n  \"Name\": \"a\",\n  \"Nested\": {\n    \"World\": \"w\"\n  }\n}\n",
+			changed:  2,
+		},
+		{
+			name:     "json without base only adds missing keys",
+			format:   "json",
+			current:  `{"Name": "edited", "Max": 50}`,
+			rendered: `{"Name": "a", "Max": 10, "Password": ""}`,
+			want:     "{\n  \"Name\": \"edited\",\n  \"Max\": 50,\n  \"Password\": \"\"\n}\n",
+			changed:  1,
+		},
+		{
+			name:     "xml config change applied, in-game edit kept",
+			format:   "xml",
+			base:     "<ServerSettings>\n\t<property name=\"ServerName\" value=\"a\"/>\n\t<property name=\"MaxPlayers\" value=\"8\"/>\n</ServerSettings>\n",
+			current:  "<ServerSettings>\n\t<!-- <property name=\"ServerName\" value=\"commented\"/> -->\n\t<property name=\"ServerName\" value=\"a\"/>\n\t<property name=\"MaxPlayers\" value=\"16\"/>\n</ServerSettings>\n",
+			rendered: "<ServerSettings>\n\t<property name=\"ServerName\" value=\"b\"/>\n\t<property name=\"MaxPlayers\" value=\"8\"/>\n</ServerSettings>\n",
+			want:     "<ServerSettings>\n\t<!-- <property name=\"ServerName\" value=\"commented\"/> -->\n\t<property name=\"ServerName\" value=\"b\"/>\n\t<property name=\"MaxPlayers\" value=\"16\"/>\n</ServerSettings>\n",
+			changed:  1,
+		},
+		{
+			name:     "xml new key added and dropped key removed",
+			format:   "xml",
+			base:     "<ServerSettings>\n\t<property name=\"Old\" value=\"1\"/>\n</ServerSettings>\n",
+			current:  "<ServerSettings>\n\t<property name=\"Old\" value=\"1\"/>\n</ServerSettings>\n",
+			rendered: "<ServerSettings>\n\t<property name=\"New\" value=\"2\"/>\n</ServerSettings>\n",
+			want:     "<ServerSettings>\n\t<property name=\"New\" value=\"2\"/>\n</ServerSettings>\n",
+			changed:  2,
+		},
+		{
+			name:     "xml without base only adds missing keys",
+			format:   "xml",
+			current:  "<ServerSettings>\n\t<property name=\"ServerName\" value=\"edited\"/>\n</ServerSettings>\n",
+			rendered: "<ServerSettings>\n\t<property name=\"ServerName\" value=\"a\"/>\n\t<property name=\"MaxPlayers\" value=\"8\"/>\n</ServerSettings>\n",
+			want:     "<ServerSettings>\n\t<property name=\"ServerName\" value=\"edited\"/>\n\t<property name=\"MaxPlayers\" value=\"8\"/>\n</ServerSettings>\n",
+			changed:  1,
+		},
+		{
+			name:     "ini keys are scoped by section",
+			format:   "ini",
+			base:     "[Server]\nName=a\nPort=7777\n\n[Game]\nName=g\n",
+			current:  "; comment\n[Server]\nName = a\nPort=7778\n\n[Game]\nName=g\n",
+			rendered: "[Server]\nName=b\nPort=7777\n\n[Game]\nName=h\nDifficulty=2\n",
+			want:     "; comment\n[Server]\nName = b\nPort=7778\n\n[Game]\nName=h\nDifficulty=2\n",
+			changed:  3,
+		},
+		{
+			name:     "ini without base only adds missing keys",
+			format:   "ini",
+			current:  "[Server]\nName=edited\n",
+			rendered: "[Server]\nName=a\nPort=7777\n\n[Game]\nDifficulty=2\n",
+			want:     "[Server]\nName=edited\nPort=7777\n\n[Game]\nDifficulty=2\n",
+			changed:  2,
+		},
+		{
+			name:     "properties config change applied, in-game edit kept",
+			format:   "properties",
+			base:     "motd=a\nmax-players=20\nold=1\n",
+			current:  "#Minecraft server properties\nmotd=a\nmax-players=50\nold=1\nlevel-seed=123\n",
+			rendered: "motd=b\nmax-players=20\n",
+			want:     "#Minecraft server properties\nmotd=b\nmax-players=50\nlevel-seed=123\n",
+			changed:  2,
+		},
+		{
+			name:     "properties without base only adds missing keys",
+			format:   "properties",
+			current:  "motd=edited\n",
+			rendered: "motd=a\npvp=true\n",
+			want:     "motd=edited\npvp=true\n",
+			changed:  1,
+		},
+		{
+			name:     "unchanged render leaves the file alone",
+			format:   "properties",
+			base:     "motd=a\n",
+			current:  "motd=edited\n",
+			rendered: "motd=a\n",
+			want:     "motd=edited\n",
+			changed:  0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var base []byte
+			if tt.base != "" {
+				base = []byte(tt.base)
+			}
+			got, changed, err := Merge(tt.format, base, []byte(tt.current), []byte(tt.rendered))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("merged:\n%s\nwant:\n%s", got, tt.want)
+			}
+			if changed != tt.changed {
+				t.Errorf("changed = %d, want %d", changed, tt.changed)
+			}
+		})
+	}
+}
+
+func TestWriteManagedKeys(t *testing.T) {
+	dir := t.TempDir()
+	state := filepath.Join(dir, "state")
+	a := File{Path: filepath.Join(dir, "a", "server.properties"), Mode: ManagedKeys, StateDir: state}
+	b := File{Path: filepath.Join(dir, "b", "server.properties"), Mode: ManagedKeys, StateDir: state}
+	for _, f := range []File{a, b} {
+		if err := os.MkdirAll(filepath.Dir(f.Path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(f.Path, []byte("motd=edited\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// No base yet: the in-game value stays and the render becomes the base
+	if _, err := a.Write([]byte("motd=a\npvp=true\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(a.Path); string(got) != "motd=edited\npvp=true\n" {
+		t.Fatalf("first write = %q", got)
+	}
+
+	// A changed render now wins; b has its own base and is untouched
+	if _, err := a.Write([]byte("motd=b\npvp=true\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(a.Path); string(got) != "motd=b\npvp=true\n" {
+		t.Fatalf("second write = %q", got)
+	}
+	if _, err := b.Write([]byte("motd=b\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(b.Path); string(got) != "motd=edited\n" {
+		t.Fatalf("b without base = %q", got)
+	}
+}
@@ -0,0 +1,148 @@
+package configfile
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	xmlCommentPattern = regexp.MustCompile(`(?s)<!--.*?-->`)
+	xmlElementPattern = regexp.MustCompile(`<([A-Za-z_][\w.-]*)((?:\s+[\w:.-]+\s*=\s*"[^"]*")*)\s*/?>`)
+	xmlAttrPattern    = regexp.MustCompile(`([\w:.-]+)\s*=\s*"([^"]*)"`)
+)
+
+// xmlDoc handles XML configs made of <property name="..." value="..."/>
+// style elements, like 7 Days to Die's serverconfig.xml. Settings are keyed by
+// their name attribute; comments and formatting are kept as they are.
+type xmlDoc struct {
+	text string
+}
+
+// xmlEntry locates the value attribute of a named element
+type xmlEntry struct {
+	key        string
+	element    string
+	start, end int // the element
+	valueStart int // the value attribute's contents
+	valueEnd   int
+}
+
+func parseXML(data []byte) *xmlDoc {
+	return &xmlDoc{text: string(data)}
+}
+
+func (d *xmlDoc) entries() []xmlEntry {
+	comments := xmlCommentPattern.FindAllStringIndex(d.text, -1)
+	inComment := func(pos int) bool {
+		for _, c := range comments {
+			if pos >= c[0] && pos < c[1] {
+				return true
+			}
+		}
+		return false
+	}
+
+	var entries []xmlEntry
+	for _, m := range xmlElementPattern.FindAllStringSubmatchIndex(d.text, -1) {
+		if inComment(m[0]) || m[4] < 0 {
+			continue
+		}
+		e := xmlEntry{element: d.text[m[2]:m[3]], start: m[0], end: m[1], valueStart: -1}
+		attrs := d.text[m[4]:m[5]]
+		for _, a := range xmlAttrPattern.FindAllStringSubmatchIndex(attrs, -1) {
+			switch attrs[a[2]:a[3]] {
+			case "name":
+				e.key = attrs[a[4]:a[5]]
+			case "value":
+				e.valueStart, e.valueEnd = m[4]+a[4], m[4]+a[5]
+			}
+		}
+		if e.key != "" && e.valueStart >= 0 {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+func (d *xmlDoc) find(key string) (xmlEntry, bool) {
+	for _, e := range d.entries() {
+		if e.key == key {
+			return e, true
+		}
+	}
+	return xmlEntry{}, false
+}
+
+func (d *xmlDoc) Keys() []string {
+	var keys []string
+	for _, e := range d.entries() {
+		keys = append(keys, e.key)
+	}
+	return keys
+}
+
+func (d *xmlDoc) Get(key string) (string, bool) {
+	e, ok := d.find(key)
+	if !ok {
+		return "", false
+	}
+	return d.text[e.valueStart:e.valueEnd], true
+}
+
+func (d *xmlDoc) Set(key, value string) {
+	if e, ok := d.find(key); ok {
+		d.text = d.text[:e.valueStart] + value + d.text[e.valueEnd:]
+		return
+	}
+
+	// New settings go before the root's closing tag, like their siblings
+	element, indent := "property", "\t"
+	if entries := d.entries(); len(entries) > 0 {
+		last := entries[len(entries)-1]
+		element = last.element
+		lineStart := strings.LastIndex(d.text[:last.start], "\n") + 1
+		indent = d.text[lineStart:last.start]
+		if strings.TrimSpace(indent) != "" {
+			indent = "\t"
+		}
+	}
+	line := fmt.Sprintf("%s<%s name=\"%s\" value=\"%s\"/>\n", indent, element, key, value)
+
+	closing := strings.LastIndex(d.text, "</")
+	if closing < 0 {
+		d.text += line
+		return
+	}
+	lineStart := strings.LastIndex(d.text[:closing], "\n") + 1
+	if strings.TrimSpace(d.text[lineStart:closing]) != "" {
+		lineStart = closing
+		line = "\n" + line
+	}
+	d.text = d.text[:lineStart] + line + d.text[lineStart:]
+}
+
+func (d *xmlDoc) Delete(key string) {
+	e, ok := d.find(key)
+	if !ok {
+		return
+	}
+	start, end := e.start, e.end
+	// Drop the whole line when the element is alone on it, trailing comment included
+	lineStart := strings.LastIndex(d.text[:start], "\n") + 1
+	lineEnd := strings.Index(d.text[end:], "\n")
+	if lineEnd < 0 {
+		lineEnd = len(d.text)
+	} else {
+		lineEnd += end + 1
+	}
+	rest := strings.TrimSpace(xmlCommentPattern.ReplaceAllString(d.text[end:lineEnd], ""))
+	if strings.TrimSpace(d.text[lineStart:start]) == "" && rest == "" {
+		start, end = lineStart, lineEnd
+	}
+	d.text = d.text[:start] + d.text[end:]
+}
+
+func (d *xmlDoc) Bytes() ([]byte, error) {
+	return []byte(d.text), nil
+}
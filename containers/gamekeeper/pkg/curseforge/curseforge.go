@@ -1,9 +1,6 @@
 package curseforge
 
 import (
-	"crypto/md5"
-	"crypto/sha1"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -16,6 +13,7 @@ import (
 	"time"
 
 	"github.com/kubelize/game-servers/gamekeeper/pkg/config"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/download"
 	"github.com/kubelize/game-servers/gamekeeper/pkg/output"
 )
 
@@ -302,6 +300,7 @@ func (c *Client) ResolveBestFile(modID int, partial string, releaseChannel strin
 // Manager handles mod installation
 type Manager struct {
 	client          *Client
+	downloader      *download.Client
 	cfg             *config.Config
 	modsDir         string
 	stateDir        string
@@ -326,10 +325,11 @@ func NewManager(cfg *config.Config, baseDir, dataDir string) (*Manager, error) {
 
 	// Hytale expects mods in ./mods relative to the server working directory
 	modsPath := cfg.GetString("HYTALE_MODS_PATH", filepath.Join(baseDir, "mods"))
-	stateDir := filepath.Join(dataDir, ".hytale-curseforge-mods")
+	stateDir := StateDir(dataDir)
 
 	m := &Manager{
 		client:          client,
+		downloader:      download.New(cfg),
 		cfg:             cfg,
 		modsDir:         modsPath,
 		stateDir:        stateDir,
@@ -474,14 +474,11 @@ func (m *Manager) downloadAndInstall(modID int, file *ModFile, manifest *Manifes
 	destPath := filepath.Join(destDir, file.FileName)
 	tmpPath := filepath.Join(m.downloadsDir, fmt.Sprintf("%d-%d.tmp", modID, file.ID))
 
-	if err := m.downloadFile(downloadURL, tmpPath); err != nil {
-		os.Remove(tmpPath)
-		return err
-	}
-
-	// Verify checksum
-	if err := m.verifyChecksum(tmpPath, file.Hashes); err != nil {
-		os.Remove(tmpPath)
+	// Partial downloads are kept and resumed; tmpPath only appears once verified
+	err = m.downloader.Get(downloadURL, tmpPath, download.Options{
+		Checksums: fileChecksums(file.Hashes),
+	})
+	if err != nil {
 		return err
 	}
 
@@ -540,59 +537,18 @@ func (m *Manager) downloadAndInstall(modID int, file *ModFile, manifest *Manifes
 	return nil
 }
 
-// downloadFile downloads a file from URL
-func (m *Manager) downloadFile(url, destPath string) error {
-	resp, err := m.client.httpClient.Get(url)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("download failed with status %d", resp.StatusCode)
-	}
-
-	out, err := os.Create(destPath)
-	if err != nil {
-		return err
-	}
-	defer out.Close()
-
-	_, err = io.Copy(out, resp.Body)
-	return err
-}
-
-// verifyChecksum verifies file checksum
-func (m *Manager) verifyChecksum(path string, hashes []Hash) error {
-	if len(hashes) == 0 {
-		return nil // No checksum to verify
-	}
-
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return err
-	}
-
+// fileChecksums converts CurseForge hashes to download checksums
+func fileChecksums(hashes []Hash) []download.Checksum {
+	var sums []download.Checksum
 	for _, h := range hashes {
-		var computed string
 		switch h.Algo {
-		case 1: // SHA1
-			sum := sha1.Sum(data)
-			computed = hex.EncodeToString(sum[:])
-		case 2: // MD5
-			sum := md5.Sum(data)
-			computed = hex.EncodeToString(sum[:])
-		default:
-			continue
-		}
-
-		if !strings.EqualFold(computed, h.Value) {
-			return fmt.Errorf("checksum mismatch: expected %s, got %s", h.Value, computed)
+		case 1:
+			sums = append(sums, download.Checksum{Algo: "sha1", Value: h.Value})
+		case 2:
+			sums = append(sums, download.Checksum{Algo: "md5", Value: h.Value})
 		}
-		return nil
 	}
-
-	return nil
+	return sums
 }
 
 // loadManifest loads or creates the manifest
@@ -0,0 +1,55 @@
+package curseforge
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/config"
+)
+
+// StateDir returns where the manifest and downloaded mod files are kept
+func StateDir(dataDir string) string {
+	return filepath.Join(dataDir, ".hytale-curseforge-mods")
+}
+
+// Relink recreates the visible mod files in the mods directory from the
+// manifest and the stored files, without contacting CurseForge. Used when
+// mods arrive through an offline bundle.
+func Relink(cfg *config.Config, baseDir, dataDir string) (int, error) {
+	// No client: this must work without network access or an API key
+	m := &Manager{
+		cfg:          cfg,
+		modsDir:      cfg.GetString("HYTALE_MODS_PATH", filepath.Join(baseDir, "mods")),
+		stateDir:     StateDir(dataDir),
+		filesDir:     filepath.Join(StateDir(dataDir), "files"),
+		manifestPath: filepath.Join(StateDir(dataDir), "manifest.json"),
+	}
+	if err := os.MkdirAll(m.modsDir, 0755); err != nil {
+		return 0, err
+	}
+
+	manifest := m.loadManifest()
+	linked := 0
+	for modID, entry := range manifest.Mods {
+		if entry.Installed == nil || entry.Resolved == nil {
+			continue
+		}
+
+		src := filepath.Join(m.filesDir, modID, strconv.Itoa(entry.Installed.FileID), entry.Resolved.FileName)
+		if _, err := os.Stat(src); err != nil {
+			return linked, fmt.Errorf("mod %s: %s is missing", modID, entry.Resolved.FileName)
+		}
+
+		visible := filepath.Join(m.modsDir, entry.Installed.Path)
+		os.Remove(visible)
+		if err := os.Symlink(src, visible); err != nil {
+			if err := copyFile(src, visible); err != nil {
+				return linked, err
+			}
+		}
+		linked++
+	}
+	return linked, nil
+}
@@ -0,0 +1,91 @@
+package download
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/cache"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/output"
+)
+
+// FromCache places an artifact at dest from the local cache or the mirror.
+// It reports false (leaving dest untouched) when neither has it.
+func (c *Client) FromCache(dest string, opts Options) bool {
+	if len(opts.Checksums) == 0 && opts.CacheKey == "" {
+		return false
+	}
+
+	if c.cache != nil {
+		if src, ok := c.lookup(opts); ok {
+			if err := cache.CopyTo(src, dest); err == nil && VerifyFile(dest, opts.Checksums...) == nil {
+				return true
+			}
+			os.Remove(dest)
+		}
+	}
+
+	if c.mirror == "" {
+		return false
+	}
+	u, err := url.Parse(c.mirrorURL(opts))
+	if err != nil {
+		return false
+	}
+
+	// One attempt only: the origin is the fallback, not more mirror retries.
+	// Origin headers (API keys) are not sent to the mirror.
+	part := dest + ".part"
+	removePart(part)
+	if err := c.fetch(u, part, Options{Progress: opts.Progress}); err != nil {
+		removePart(part)
+		return false
+	}
+	if err := VerifyFile(part, opts.Checksums...); err != nil {
+		removePart(part)
+		return false
+	}
+	os.Remove(partStatePath(part))
+	if err := os.Rename(part, dest); err != nil {
+		return false
+	}
+	c.Remember(dest, opts)
+	return true
+}
+
+// Remember stores a finished artifact in the local cache. Artifacts without
+// a checksum or key are skipped since nothing could look them up again.
+func (c *Client) Remember(file string, opts Options) {
+	if c.cache == nil || (len(opts.Checksums) == 0 && opts.CacheKey == "") {
+		return
+	}
+
+	var keys []string
+	if opts.CacheKey != "" {
+		keys = append(keys, opts.CacheKey)
+	}
+	if _, err := c.cache.Store(file, keys...); err != nil {
+		output.Warning(fmt.Sprintf("failed to cache %s: %v", file, err))
+	}
+}
+
+// lookup finds an artifact in the local cache by checksum, then by key
+func (c *Client) lookup(opts Options) (string, bool) {
+	for _, sum := range opts.Checksums {
+		if p, ok := c.cache.Path(sum.Algo, sum.Value); ok {
+			return p, true
+		}
+	}
+	if opts.CacheKey != "" {
+		return c.cache.KeyPath(opts.CacheKey)
+	}
+	return "", false
+}
+
+// mirrorURL returns the mirror location of an artifact (see cache.Handler)
+func (c *Client) mirrorURL(opts Options) string {
+	if len(opts.Checksums) > 0 {
+		return fmt.Sprintf("%s/%s/%s", c.mirror, opts.Checksums[0].Algo, opts.Checksums[0].Value)
+	}
+	return fmt.Sprintf("%s/keys/%s", c.mirror, cache.KeyDigest(opts.CacheKey))
+}
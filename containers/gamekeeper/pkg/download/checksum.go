@@ -0,0 +1,97 @@
+package download
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+)
+
+// Checksum is an expected digest of a downloaded file
+type Checksum struct {
+	Algo  string
+	Value string
+}
+
+// ChecksumError reports a digest mismatch
+type ChecksumError struct {
+	Algo     string
+	Expected string
+	Actual   string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("%s checksum mismatch: expected %s, got %s", e.Algo, e.Expected, e.Actual)
+}
+
+// ParseChecksum parses "algo:hex"; a bare digest is treated as sha256.
+// An empty string yields no checksums.
+func ParseChecksum(s string) ([]Checksum, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+
+	c := Checksum{Algo: "sha256", Value: s}
+	if i := strings.Index(s, ":"); i >= 0 {
+		c = Checksum{Algo: strings.ToLower(s[:i]), Value: s[i+1:]}
+	}
+	if newHash(c.Algo) == nil {
+		return nil, fmt.Errorf("unsupported checksum algorithm: %s", c.Algo)
+	}
+	return []Checksum{c}, nil
+}
+
+// VerifyFile checks path against every checksum in a single pass
+func VerifyFile(path string, sums ...Checksum) error {
+	if len(sums) == 0 {
+		return nil
+	}
+
+	hashes := make([]hash.Hash, len(sums))
+	writers := make([]io.Writer, len(sums))
+	for i, c := range sums {
+		h := newHash(c.Algo)
+		if h == nil {
+			return fmt.Errorf("unsupported checksum algorithm: %s", c.Algo)
+		}
+		hashes[i], writers[i] = h, h
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := io.Copy(io.MultiWriter(writers...), f); err != nil {
+		return err
+	}
+
+	for i, c := range sums {
+		actual := hex.EncodeToString(hashes[i].Sum(nil))
+		if !strings.EqualFold(actual, c.Value) {
+			return &ChecksumError{Algo: c.Algo, Expected: c.Value, Actual: actual}
+		}
+	}
+	return nil
+}
+
+func newHash(algo string) hash.Hash {
+	switch strings.ToLower(algo) {
+	case "sha256":
+		return sha256.New()
+	case "sha512":
+		return sha512.New()
+	case "sha1":
+		return sha1.New()
+	case "md5":
+		return md5.New()
+	}
+	return nil
+}
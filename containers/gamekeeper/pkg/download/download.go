@@ -0,0 +1,345 @@
+package download
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/cache"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/config"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/output"
+)
+
+// ProgressFunc reports download progress in bytes; total is -1 when unknown
+type ProgressFunc func(done, total int64)
+
+// Options controls a single download
+type Options struct {
+	// Checksums the finished file must match; all of them are verified
+	Checksums []Checksum
+	// Header is added to every request
+	Header http.Header
+	// Progress is called as data arrives; may be nil
+	Progress ProgressFunc
+	// CacheKey names artifacts that have no known checksum (e.g. "hytale-server/<version>")
+	CacheKey string
+}
+
+// Client downloads files with resume, retries and checksum verification
+type Client struct {
+	httpClient *http.Client
+	retries    int
+	backoff    time.Duration
+	maxBackoff time.Duration
+	limits     *hostLimits
+	cache      *cache.Cache
+	mirror     string
+}
+
+// New creates a download client configured from DOWNLOAD_* settings
+func New(cfg *config.Config) *Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = 60 * time.Second
+	transport.DialContext = (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+
+	return &Client{
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   time.Duration(cfg.GetInt("DOWNLOAD_TIMEOUT_MINUTES", 30)) * time.Minute,
+		},
+		retries:    cfg.GetInt("DOWNLOAD_RETRIES", 4),
+		backoff:    time.Duration(cfg.GetInt("DOWNLOAD_BACKOFF_SECONDS", 2)) * time.Second,
+		maxBackoff: 60 * time.Second,
+		limits:     limitsFor(cfg.GetString("DOWNLOAD_RATE_LIMIT", ""), cfg.GetString("DOWNLOAD_HOST_RATE_LIMITS", "")),
+		cache:      cache.New(cfg),
+		mirror:     strings.TrimSuffix(cfg.GetString("ARTIFACT_MIRROR_URL", ""), "/"),
+	}
+}
+
+// Get downloads rawURL to dest. The artifact cache and mirror are tried
+// first. Data is written to dest.part, which survives failed attempts so the
+// next one resumes with a Range request guarded by If-Range; dest only
+// appears once the file is complete and verified.
+func (c *Client) Get(rawURL, dest string, opts Options) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid url %s: %w", rawURL, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+
+	if c.FromCache(dest, opts) {
+		return nil
+	}
+	if err := c.get(u, dest, opts); err != nil {
+		return err
+	}
+	c.Remember(dest, opts)
+	return nil
+}
+
+// get downloads u to dest with retries
+func (c *Client) get(u *url.URL, dest string, opts Options) error {
+	part := dest + ".part"
+	var lastErr error
+	checksumRetried := false
+	for attempt := 0; attempt <= c.retries; attempt++ {
+		if attempt > 0 {
+			wait := c.backoffFor(attempt, lastErr)
+			fmt.Println()
+			output.Warning(fmt.Sprintf("%v - retrying in %s", lastErr, wait))
+			time.Sleep(wait)
+		}
+
+		err := c.fetch(u, part, opts)
+		if err == nil {
+			err = VerifyFile(part, opts.Checksums...)
+			if err != nil {
+				// A corrupt partial can't be resumed; start over
+				removePart(part)
+			}
+		}
+		if err == nil {
+			os.Remove(partStatePath(part))
+			return os.Rename(part, dest)
+		}
+
+		lastErr = err
+		var ce *ChecksumError
+		if errors.As(err, &ce) {
+			// One fresh download covers a corrupted resume; more won't help
+			if checksumRetried {
+				break
+			}
+			checksumRetried = true
+			continue
+		}
+		if !retryable(err) {
+			break
+		}
+	}
+
+	return lastErr
+}
+
+// fetch downloads (or resumes) into part. A partial file is only resumed
+// when its state file names the same URL and a validator for If-Range, so a
+// changed artifact is downloaded again rather than spliced onto old data.
+func (c *Client) fetch(u *url.URL, part string, opts Options) error {
+	var offset int64
+	if info, err := os.Stat(part); err == nil {
+		offset = info.Size()
+	}
+	state, ok := readPartState(part)
+	if offset > 0 && (!ok || state.URL != urlDigest(u) || state.validator() == "") {
+		removePart(part)
+		offset = 0
+	}
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range opts.Header {
+		req.Header[k] = v
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", state.validator())
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return &retryError{err: err}
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		flags |= os.O_APPEND
+	case resp.StatusCode == http.StatusOK:
+		// Server ignored the range, the file changed, or there was no range;
+		// start from scratch
+		flags |= os.O_TRUNC
+		offset = 0
+		if err := writePartState(part, u, resp); err != nil {
+			return err
+		}
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// The partial file is complete only if it is still the same file
+		if resp.Header.Get("Content-Range") == fmt.Sprintf("bytes */%d", offset) && state.matches(resp) {
+			return nil
+		}
+		removePart(part)
+		return &retryError{err: fmt.Errorf("partial download no longer matches the remote file")}
+	default:
+		err := fmt.Errorf("download of %s failed with status %s", u.Redacted(), resp.Status)
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			return &retryError{err: err, after: retryAfter(resp)}
+		}
+		return err
+	}
+
+	out, err := os.OpenFile(part, flags, 0644)
+	if err != nil {
+		return err
+	}
+
+	total := int64(-1)
+	if resp.ContentLength >= 0 {
+		total = offset + resp.ContentLength
+	}
+	w := &progressWriter{w: out, done: offset, total: total, progress: opts.Progress}
+
+	_, copyErr := io.Copy(w, c.limits.reader(u.Hostname(), resp.Body))
+	closeErr := out.Close()
+	if copyErr != nil {
+		return &retryError{err: fmt.Errorf("download of %s interrupted: %w", u.Redacted(), copyErr)}
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+	if total >= 0 && w.done != total {
+		return &retryError{err: fmt.Errorf("download of %s ended early (%d of %d bytes)", u.Redacted(), w.done, total)}
+	}
+	return nil
+}
+
+// partState records where a partial download came from, next to it in
+// dest.part.state
+type partState struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+// validator returns the If-Range value: a strong ETag, else Last-Modified
+func (s partState) validator() string {
+	if s.ETag != "" && !strings.HasPrefix(s.ETag, "W/") {
+		return s.ETag
+	}
+	return s.LastModified
+}
+
+// matches reports whether resp carries the validator the state was saved with
+func (s partState) matches(resp *http.Response) bool {
+	if s.ETag != "" && resp.Header.Get("ETag") == s.ETag {
+		return true
+	}
+	return s.LastModified != "" && resp.Header.Get("Last-Modified") == s.LastModified
+}
+
+func partStatePath(part string) string {
+	return part + ".state"
+}
+
+func readPartState(part string) (partState, bool) {
+	var s partState
+	data, err := os.ReadFile(partStatePath(part))
+	if err != nil || json.Unmarshal(data, &s) != nil {
+		return partState{}, false
+	}
+	return s, true
+}
+
+// writePartState saves the validators of a fresh download; without any the
+// partial file can't be resumed safely and the state is removed
+func writePartState(part string, u *url.URL, resp *http.Response) error {
+	s := partState{
+		URL:          urlDigest(u),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	if s.validator() == "" {
+		if err := os.Remove(partStatePath(part)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(partStatePath(part), data, 0644)
+}
+
+// removePart removes a partial download and its state
+func removePart(part string) {
+	os.Remove(part)
+	os.Remove(partStatePath(part))
+}
+
+// urlDigest identifies a URL without keeping tokens from its query on disk
+func urlDigest(u *url.URL) string {
+	sum := sha256.Sum256([]byte(u.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// backoffFor returns the delay before the given attempt, honouring Retry-After
+func (c *Client) backoffFor(attempt int, err error) time.Duration {
+	var re *retryError
+	if errors.As(err, &re) && re.after > 0 {
+		return re.after
+	}
+	wait := c.backoff << (attempt - 1)
+	if wait > c.maxBackoff || wait <= 0 {
+		wait = c.maxBackoff
+	}
+	return wait
+}
+
+// retryError marks failures worth another attempt
+type retryError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *retryError) Error() string { return e.err.Error() }
+func (e *retryError) Unwrap() error { return e.err }
+
+func retryable(err error) bool {
+	var re *retryError
+	return errors.As(err, &re)
+}
+
+// retryAfter parses a Retry-After header given in seconds
+func retryAfter(resp *http.Response) time.Duration {
+	secs, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || secs <= 0 {
+		return 0
+	}
+	return time.Duration(secs) * time.Second
+}
+
+// progressWriter counts written bytes and reports them
+type progressWriter struct {
+	w        io.Writer
+	done     int64
+	total    int64
+	progress ProgressFunc
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.done += int64(n)
+	if p.progress != nil {
+		p.progress(p.done, p.total)
+	}
+	return n, err
+}
@@ -0,0 +1,247 @@
+package download
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// rangeServer serves content with an ETag, honouring Range and If-Range the
+// way CDNs do, and records the Range header of every request
+type rangeServer struct {
+	mu      sync.Mutex
+	content string
+	etag    string
+	ranges  []string
+}
+
+func (s *rangeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ranges = append(s.ranges, r.Header.Get("Range"))
+
+	w.Header().Set("ETag", s.etag)
+	rng := r.Header.Get("Range")
+	if rng == "" || (r.Header.Get("If-Range") != "" && r.Header.Get("If-Range") != s.etag) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(s.content)))
+		w.Write([]byte(s.content))
+		return
+	}
+	offset, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rng, "bytes="), "-"))
+	if offset >= len(s.content) {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", len(s.content)))
+		w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+	w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, len(s.content)-1, len(s.content)))
+	w.Header().Set("Content-Length", strconv.Itoa(len(s.content)-offset))
+	w.WriteHeader(http.StatusPartialContent)
+	w.Write([]byte(s.content[offset:]))
+}
+
+func testClient(srv *httptest.Server) *Client {
+	return &Client{
+		httpClient: srv.Client(),
+		retries:    1,
+		backoff:    time.Millisecond,
+		maxBackoff: time.Millisecond,
+		limits:     limitsFor("", ""),
+	}
+}
+
+func sha256Of(s string) Checksum {
+	sum := sha256.Sum256([]byte(s))
+	return Checksum{Algo: "sha256", Value: hex.EncodeToString(sum[:])}
+}
+
+func TestGetResume(t *testing.T) {
+	const content = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+	tests := []struct {
+		name string
+		// part is left over from an earlier attempt, with state naming etag
+		// and the URL (sameURL) or another one
+		part      string
+		partETag  string
+		withState bool
+		sameURL   bool
+		// etag the server now reports for the file
+		etag      string
+		wantRange string
+	}{
+		{name: "fresh download", etag: `"v1"`},
+		{name: "resumes the same file", part: content[:10], partETag: `"v1"`, withState: true, sameURL: true, etag: `"v1"`, wantRange: "bytes=10-"},
+		{name: "restarts a changed file", part: "OLDOLDOLDO", partETag: `"v0"`, withState: true, sameURL: true, etag: `"v1"`, wantRange: "bytes=10-"},
+		{name: "drops a partial without state", part: content[:10], etag: `"v1"`},
+		{name: "drops a partial of another URL", part: content[:10], partETag: `"v1"`, withState: true, etag: `"v1"`},
+		{name: "accepts a complete partial", part: content, partETag: `"v1"`, withState: true, sameURL: true, etag: `"v1"`, wantRange: fmt.Sprintf("bytes=%d-", len(content))},
+		{name: "restarts a complete partial of a changed file", part: strings.Repeat("x", len(content)), partETag: `"v0"`, withState: true, sameURL: true, etag: `"v1"`, wantRange: fmt.Sprintf("bytes=%d-", len(content))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs := &rangeServer{content: content, etag: tt.etag}
+			srv := httptest.NewServer(rs)
+			defer srv.Close()
+
+			dest := filepath.Join(t.TempDir(), "server.zip")
+			part := dest + ".part"
+			if tt.part != "" {
+				if err := os.WriteFile(part, []byte(tt.part), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.withState {
+				stateURL, _ := url.Parse(srv.URL + "/other")
+				if tt.sameURL {
+					stateURL, _ = url.Parse(srv.URL + "/file")
+				}
+				state := fmt.Sprintf(`{"url":%q,"etag":%q}`, urlDigest(stateURL), tt.partETag)
+				if err := os.WriteFile(partStatePath(part), []byte(state), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// A changed file only shows up as such through its checksum
+			// when the partial is already complete
+			opts := Options{Checksums: []Checksum{sha256Of(content)}}
+			if err := testClient(srv).Get(srv.URL+"/file", dest, opts); err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+
+			got, err := os.ReadFile(dest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != content {
+				t.Errorf("content = %q, want %q", got, content)
+			}
+			if rs.ranges[0] != tt.wantRange {
+				t.Errorf("first Range = %q, want %q", rs.ranges[0], tt.wantRange)
+			}
+			for _, leftover := range []string{part, partStatePath(part)} {
+				if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+					t.Errorf("%s left behind", filepath.Base(leftover))
+				}
+			}
+		})
+	}
+}
+
+func TestGetWithoutValidatorDoesNotResume(t *testing.T) {
+	const content = "0123456789abcdefghijklmnopqrstuvwxyz"
+	var ranges []string
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		attempts++
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		if attempts == 1 {
+			// Cut the first attempt short
+			w.Write([]byte(content[:10]))
+			if hj, ok := w.(http.Hijacker); ok {
+				conn, _, _ := hj.Hijack()
+				conn.Close()
+			}
+			return
+		}
+		w.Write([]byte(content))
+	}))
+	defer srv.Close()
+
+	dest := filepath.Join(t.TempDir(), "server.zip")
+	if err := testClient(srv).Get(srv.URL, dest, Options{}); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	got, _ := os.ReadFile(dest)
+	if string(got) != content {
+		t.Errorf("content = %q, want %q", got, content)
+	}
+	for _, r := range ranges {
+		if r != "" {
+			t.Errorf("resumed with Range %q although the server gave no validator", r)
+		}
+	}
+}
+
+func TestGetChecksum(t *testing.T) {
+	const content = "server build"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(content))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		sums    []Checksum
+		wantErr bool
+	}{
+		{name: "no checksum"},
+		{name: "matching sha256", sums: []Checksum{sha256Of(content)}},
+		{name: "matching sha256, uppercase", sums: []Checksum{{Algo: "sha256", Value: strings.ToUpper(sha256Of(content).Value)}}},
+		{name: "mismatch", sums: []Checksum{sha256Of("something else")}, wantErr: true},
+		{name: "one of several mismatches", sums: []Checksum{sha256Of(content), {Algo: "md5", Value: "00000000000000000000000000000000"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "server.jar")
+			err := testClient(srv).Get(srv.URL, dest, Options{Checksums: tt.sums})
+			if tt.wantErr {
+				var ce *ChecksumError
+				if !errors.As(err, &ce) {
+					t.Fatalf("Get error = %v, want a ChecksumError", err)
+				}
+				for _, path := range []string{dest, dest + ".part"} {
+					if _, err := os.Stat(path); !os.IsNotExist(err) {
+						t.Errorf("%s exists after a checksum mismatch", filepath.Base(path))
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if got, _ := os.ReadFile(dest); string(got) != content {
+				t.Errorf("content = %q, want %q", got, content)
+			}
+		})
+	}
+}
+
+func TestParseChecksum(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []Checksum
+		wantErr bool
+	}{
+		{in: "", want: nil},
+		{in: "  ", want: nil},
+		{in: "abc123", want: []Checksum{{Algo: "sha256", Value: "abc123"}}},
+		{in: "SHA1:abc123", want: []Checksum{{Algo: "sha1", Value: "abc123"}}},
+		{in: "sha512:abc123", want: []Checksum{{Algo: "sha512", Value: "abc123"}}},
+		{in: "crc32:abc123", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseChecksum(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseChecksum(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("ParseChecksum(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package download
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hostLimits throttles download bandwidth per host. Limits are shared by
+// every client in the process, so parallel downloads from one host split it.
+type hostLimits struct {
+	defaultRate int64
+	rates       map[string]int64
+}
+
+var (
+	bucketsMu sync.Mutex
+	buckets   = map[string]*bucket{}
+)
+
+// limitsFor parses DOWNLOAD_RATE_LIMIT ("10MB") and DOWNLOAD_HOST_RATE_LIMITS
+// ("edge.forgecdn.net=5MB,cdn.example.com=500KB"); sizes are per second
+func limitsFor(defaultLimit, hostLimitList string) *hostLimits {
+	l := &hostLimits{
+		defaultRate: parseRate(defaultLimit),
+		rates:       map[string]int64{},
+	}
+	for _, entry := range strings.Split(hostLimitList, ",") {
+		host, rate, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			continue
+		}
+		if r := parseRate(rate); r > 0 {
+			l.rates[strings.ToLower(strings.TrimSpace(host))] = r
+		}
+	}
+	return l
+}
+
+// reader wraps r with the limit for host, if any
+func (l *hostLimits) reader(host string, r io.Reader) io.Reader {
+	host = strings.ToLower(host)
+	rate, ok := l.rates[host]
+	if !ok {
+		rate = l.defaultRate
+	}
+	if rate <= 0 {
+		return r
+	}
+
+	bucketsMu.Lock()
+	b, ok := buckets[host]
+	if !ok || b.rate != rate {
+		b = &bucket{rate: rate, tokens: rate, last: time.Now()}
+		buckets[host] = b
+	}
+	bucketsMu.Unlock()
+
+	return &limitedReader{r: r, bucket: b}
+}
+
+// bucket is a token bucket refilled at rate bytes per second
+type bucket struct {
+	mu     sync.Mutex
+	rate   int64
+	tokens int64
+	last   time.Time
+}
+
+// take blocks until n bytes may be read
+func (b *bucket) take(n int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens += int64(now.Sub(b.last).Seconds() * float64(b.rate))
+	if b.tokens > b.rate {
+		b.tokens = b.rate
+	}
+	b.last = now
+
+	b.tokens -= n
+	if b.tokens < 0 {
+		wait := time.Duration(float64(-b.tokens) / float64(b.rate) * float64(time.Second))
+		time.Sleep(wait)
+		b.tokens = 0
+		b.last = time.Now()
+	}
+}
+
+type limitedReader struct {
+	r      io.Reader
+	bucket *bucket
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	// Keep reads small enough that throttling stays smooth
+	if max := int(l.bucket.rate / 4); max > 0 && len(p) > max {
+		p = p[:max]
+	}
+	n, err := l.r.Read(p)
+	if n > 0 {
+		l.bucket.take(int64(n))
+	}
+	return n, err
+}
+
+// parseRate parses a byte size such as "512KB", "10MB" or "1048576"
+func parseRate(s string) int64 {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "/S"), "PS")
+	mult := int64(1)
+	for _, u := range []struct {
+		suffix string
+		mult   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(s, u.suffix) {
+			s, mult = strings.TrimSuffix(s, u.suffix), u.mult
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || n <= 0 {
+		return 0
+	}
+	return int64(n * float64(mult))
+}
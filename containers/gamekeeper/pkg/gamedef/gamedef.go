@@ -0,0 +1,303 @@
+package gamedef
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/configfile"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultDir is where images put additional definitions
+const DefaultDir = "/usr/local/share/game-definitions"
+
+// Capability is an optional feature a game supports
+type Capability string
+
+const (
+	// CapabilityMods installs mods from config
+	CapabilityMods Capability = "mods"
+	// CapabilityRCON accepts remote console commands
+	CapabilityRCON Capability = "rcon"
+	// CapabilityQuery answers server browser queries
+	CapabilityQuery Capability = "query"
+	// CapabilityBackups backs up its world on its own
+	CapabilityBackups Capability = "backups"
+)
+
+// Capabilities lists every capability, in display order
+var Capabilities = []Capability{CapabilityMods, CapabilityRCON, CapabilityQuery, CapabilityBackups}
+
+// builtin holds the definitions shipped with gamekeeper
+//
+//go:embed games/*.yaml
+var builtin embed.FS
+
+// Definition describes a game server declaratively: where its files come
+// from, how it is started and configured, and how its console is driven.
+// String fields used at runtime (URLs, the start command and args, template
+// paths) are templates rendered with the config values, like the game config
+// templates.
+type Definition struct {
+	Name        string   `yaml:"name"`
+	DisplayName string   `yaml:"display_name"`
+	Aliases     []string `yaml:"aliases"`
+	// Capabilities are the optional features the game supports
+	Capabilities []Capability `yaml:"capabilities"`
+	// Defaults are config values used when config doesn't set them
+	Defaults  map[string]string `yaml:"defaults"`
+	Install   Install           `yaml:"install"`
+	Start     Start             `yaml:"start"`
+	Templates []Template        `yaml:"templates"`
+	Ports     []Port            `yaml:"ports"`
+	Ready     Ready             `yaml:"ready"`
+	Console   Console           `yaml:"console"`
+	// WorldPaths are world/save data (relative to the server directory),
+	// kept across updates and snapshotted for rollback. They are templates,
+	// so a game whose saves move with a setting can follow it.
+	WorldPaths []string `yaml:"world_paths"`
+	// PreservePaths are other paths an update must not replace
+	PreservePaths []string `yaml:"preserve_paths"`
+
+	// Source is the file the definition was loaded from
+	Source string `yaml:"-"`
+}
+
+// Install is where the server files come from; exactly one source is set
+type Install struct {
+	Steam *SteamInstall `yaml:"steam"`
+	URL   *URLInstall   `yaml:"url"`
+	Java  *JavaInstall  `yaml:"java"`
+}
+
+// SteamInstall installs a dedicated server with steamcmd
+type SteamInstall struct {
+	AppID  int    `yaml:"app_id"`
+	Branch string `yaml:"branch"`
+	// WorkshopAppID is the game client's app ID workshop items are published
+	// under; 0 means the game has no workshop support
+	WorkshopAppID int `yaml:"workshop_app_id"`
+}
+
+// URLInstall downloads a file, extracting it when it is an archive
+type URLInstall struct {
+	URL      string `yaml:"url"`
+	Checksum string `yaml:"checksum"`
+	// Version labels the build; defaults to the file name of the URL
+	Version string `yaml:"version"`
+}
+
+// JavaInstall downloads a server jar and starts it with java
+type JavaInstall struct {
+	URL      string `yaml:"url"`
+	Checksum string `yaml:"checksum"`
+	Version  string `yaml:"version"`
+	// Jar is the file name the jar is saved as, relative to the server directory
+	Jar string `yaml:"jar"`
+}
+
+// Start is the server command line, run in the server directory. Args that
+// render empty are dropped, so optional flags can be written as
+// {{ if ... }}--flag{{ end }}.
+type Start struct {
+	Command string            `yaml:"command"`
+	Args    []string          `yaml:"args"`
+	Env     map[string]string `yaml:"env"`
+}
+
+// Template is a config file rendered from the config values
+type Template struct {
+	Source string `yaml:"source"`
+	Target string `yaml:"target"`
+	// Mode is seed-once, always-overwrite or managed-keys (the default)
+	Mode string `yaml:"mode"`
+}
+
+// Port is a port the server listens on
+type Port struct {
+	Name     string `yaml:"name"`
+	Port     int    `yaml:"port"`
+	Protocol string `yaml:"protocol"`
+	// ConfigKey overrides the port from config
+	ConfigKey string `yaml:"config_key"`
+}
+
+// Ready tells when the server has finished starting
+type Ready struct {
+	LogPattern string `yaml:"log_pattern"`
+}
+
+// Console holds the console commands used to save and stop the server.
+// Without a stop command the server is stopped with Ctrl-C.
+type Console struct {
+	Save string `yaml:"save"`
+	Stop string `yaml:"stop"`
+}
+
+// Load returns the built-in definitions, overridden and extended by the
+// *.yaml files in dir (when it exists), sorted by name
+func Load(dir string) ([]*Definition, error) {
+	byName := make(map[string]*Definition)
+
+	files, _ := fs.Glob(builtin, "games/*.yaml")
+	for _, f := range files {
+		data, err := builtin.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		def, err := Parse(data, "builtin:"+f)
+		if err != nil {
+			return nil, err
+		}
+		byName[def.Name] = def
+	}
+
+	if dir != "" {
+		paths, _ := filepath.Glob(filepath.Join(dir, "*.yaml"))
+		for _, path := range paths {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read game definition: %w", err)
+			}
+			def, err := Parse(data, path)
+			if err != nil {
+				return nil, err
+			}
+			byName[def.Name] = def
+		}
+	}
+
+	defs := make([]*Definition, 0, len(byName))
+	for _, def := range byName {
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+	return defs, nil
+}
+
+// Parse decodes and validates a definition; source names it in errors
+func Parse(data []byte, source string) (*Definition, error) {
+	var def Definition
+	dec := yaml.NewDecoder(strings.NewReader(string(data)))
+	dec.KnownFields(true)
+	if err := dec.Decode(&def); err != nil {
+		return nil, fmt.Errorf("invalid game definition %s: %w", source, err)
+	}
+	def.Source = source
+	if err := def.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid game definition %s:\n%w", source, err)
+	}
+	return &def, nil
+}
+
+// Validate reports every problem with the definition at once
+func (d *Definition) Validate() error {
+	var errs []error
+	if d.Name == "" {
+		errs = append(errs, errors.New("name is required"))
+	}
+
+	for _, c := range d.Capabilities {
+		if !c.Known() {
+			errs = append(errs, fmt.Errorf("unknown capability %q", c))
+		}
+	}
+
+	sources := 0
+	if s := d.Install.Steam; s != nil {
+		sources++
+		if s.AppID <= 0 {
+			errs = append(errs, errors.New("install.steam.app_id is required"))
+		}
+	}
+	if u := d.Install.URL; u != nil {
+		sources++
+		if u.URL == "" {
+			errs = append(errs, errors.New("install.url.url is required"))
+		}
+	}
+	if j := d.Install.Java; j != nil {
+		sources++
+		if j.URL == "" {
+			errs = append(errs, errors.New("install.java.url is required"))
+		}
+	}
+	if sources != 1 {
+		errs = append(errs, errors.New("install needs exactly one of steam, url or java"))
+	}
+
+	if d.Start.Command == "" && d.Install.Java == nil {
+		errs = append(errs, errors.New("start.command is required"))
+	}
+
+	for i, t := range d.Templates {
+		if t.Source == "" || t.Target == "" {
+			errs = append(errs, fmt.Errorf("templates[%d]: source and target are required", i))
+		}
+		if t.Mode != "" {
+			if _, err := configfile.ParseMode(t.Mode); err != nil {
+				errs = append(errs, fmt.Errorf("templates[%d]: %w", i, err))
+			}
+		}
+	}
+
+	for i, p := range d.Ports {
+		if p.Port < 1 || p.Port > 65535 {
+			errs = append(errs, fmt.Errorf("ports[%d]: %d is not a valid port", i, p.Port))
+		}
+		switch p.Protocol {
+		case "", "tcp", "udp":
+		default:
+			errs = append(errs, fmt.Errorf("ports[%d]: protocol must be tcp or udp", i))
+		}
+	}
+
+	if d.Ready.LogPattern != "" {
+		if _, err := regexp.Compile(d.Ready.LogPattern); err != nil {
+			errs = append(errs, fmt.Errorf("ready.log_pattern: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Known reports whether c is one of Capabilities
+func (c Capability) Known() bool {
+	for _, known := range Capabilities {
+		if c == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Title returns the display name, or the name when none is set
+func (d *Definition) Title() string {
+	if d.DisplayName != "" {
+		return d.DisplayName
+	}
+	return d.Name
+}
+
+// ConfigMode returns the template's mode, managed-keys when unset
+func (t Template) ConfigMode() configfile.Mode {
+	mode, err := configfile.ParseMode(t.Mode)
+	if err != nil {
+		return configfile.ManagedKeys
+	}
+	return mode
+}
+
+// ProtocolName returns the port's protocol, tcp when unset
+func (p Port) ProtocolName() string {
+	if p.Protocol == "" {
+		return "tcp"
+	}
+	return p.Protocol
+}
@@ -0,0 +1,193 @@
+package mcdist
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/config"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/mojang"
+)
+
+// Server distribution types (MINECRAFT_TYPE)
+const (
+	Vanilla  = "vanilla"
+	Paper    = "paper"
+	Purpur   = "purpur"
+	Fabric   = "fabric"
+	Forge    = "forge"
+	NeoForge = "neoforge"
+)
+
+// Release is a resolved server distribution
+type Release struct {
+	Type        string
+	GameVersion string
+	// Build is the flavor's build or loader version; empty for vanilla
+	Build string
+	URL   string
+	// Checksum is "algo:hex", or empty when the project publishes none
+	Checksum string
+	// Installer means URL is an installer jar to run with --installServer
+	Installer bool
+}
+
+// ID identifies the release in the build history: the plain game version for
+// vanilla, "<type>/<game version>/<build>" otherwise
+func (r *Release) ID() string {
+	if r.Type == Vanilla {
+		return r.GameVersion
+	}
+	return fmt.Sprintf("%s/%s/%s", r.Type, r.GameVersion, r.Build)
+}
+
+// ParseID splits an ID made by Release.ID
+func ParseID(id string) (typ, gameVersion, build string) {
+	parts := strings.SplitN(id, "/", 3)
+	if len(parts) != 3 {
+		return Vanilla, id, ""
+	}
+	return parts[0], parts[1], parts[2]
+}
+
+// Resolver finds the download for a game version and build; both accept "latest"
+type Resolver interface {
+	Resolve(gameVersion, build string) (*Release, error)
+}
+
+// NewResolver returns the resolver for a MINECRAFT_TYPE
+func NewResolver(cfg *config.Config, typ string) (Resolver, error) {
+	h := &httpClient{client: &http.Client{Timeout: 30 * time.Second}}
+
+	var r Resolver
+	switch strings.ToLower(typ) {
+	case "", Vanilla:
+		return &vanillaResolver{client: mojang.NewClient(cfg)}, nil
+	case Paper:
+		r = &paperResolver{http: h, base: strings.TrimSuffix(cfg.GetString("PAPER_API_URL", "https://api.papermc.io"), "/")}
+	case Purpur:
+		r = &purpurResolver{http: h, base: strings.TrimSuffix(cfg.GetString("PURPUR_API_URL", "https://api.purpurmc.org"), "/")}
+	case Fabric:
+		r = &fabricResolver{http: h, base: strings.TrimSuffix(cfg.GetString("FABRIC_META_URL", "https://meta.fabricmc.net"), "/")}
+	case Forge:
+		r = &forgeResolver{
+			http:       h,
+			maven:      strings.TrimSuffix(cfg.GetString("FORGE_MAVEN_URL", "https://maven.minecraftforge.net"), "/"),
+			promotions: cfg.GetString("FORGE_PROMOTIONS_URL", "https://files.minecraftforge.net/net/minecraftforge/forge/promotions_slim.json"),
+		}
+	case NeoForge:
+		r = &neoForgeResolver{http: h, maven: strings.TrimSuffix(cfg.GetString("NEOFORGE_MAVEN_URL", "https://maven.neoforged.net/releases"), "/")}
+	default:
+		return nil, fmt.Errorf("unsupported MINECRAFT_TYPE: %s", typ)
+	}
+	return &releasesOnly{Resolver: r, typ: strings.ToLower(typ)}, nil
+}
+
+// releasesOnly rejects snapshot keywords, which only the Mojang manifest can
+// resolve; the flavor APIs list release versions only
+type releasesOnly struct {
+	Resolver
+	typ string
+}
+
+func (r *releasesOnly) Resolve(gameVersion, build string) (*Release, error) {
+	switch strings.ToLower(gameVersion) {
+	case "latest-snapshot", "snapshot":
+		return nil, fmt.Errorf("MINECRAFT_VERSION=%s is only supported for MINECRAFT_TYPE=vanilla, not %s; set a release version or latest", gameVersion, r.typ)
+	}
+	return r.Resolver.Resolve(gameVersion, build)
+}
+
+// IsRelease reports whether a game version is a plain release ("1.21.1",
+// "26.1"), as opposed to a snapshot, pre-release or release candidate
+func IsRelease(gameVersion string) bool {
+	_, ok := releaseParts(gameVersion)
+	return ok
+}
+
+// releaseParts splits a release version into its numeric parts
+func releaseParts(gameVersion string) ([]int, bool) {
+	fields := strings.Split(gameVersion, ".")
+	if len(fields) < 2 {
+		return nil, false
+	}
+	parts := make([]int, len(fields))
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 || strings.HasPrefix(f, "+") {
+			return nil, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
+
+// isLatest reports whether a version setting asks for the newest one
+func isLatest(v string) bool {
+	switch strings.ToLower(v) {
+	case "", "latest", "release", "latest-release":
+		return true
+	}
+	return false
+}
+
+type httpClient struct {
+	client *http.Client
+}
+
+func (h *httpClient) get(url string) ([]byte, error) {
+	resp, err := h.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s failed with status %d", url, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (h *httpClient) getJSON(url string, v interface{}) error {
+	data, err := h.get(url)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid response from %s: %w", url, err)
+	}
+	return nil
+}
+
+// mavenVersions lists the versions in a maven-metadata.xml
+func (h *httpClient) mavenVersions(url string) ([]string, error) {
+	data, err := h.get(url)
+	if err != nil {
+		return nil, err
+	}
+	var meta struct {
+		Versions []string `xml:"versioning>versions>version"`
+	}
+	if err := xml.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("invalid maven metadata from %s: %w", url, err)
+	}
+	return meta.Versions, nil
+}
+
+// mavenSHA1 fetches the .sha1 file maven publishes next to an artifact
+func (h *httpClient) mavenSHA1(url string) string {
+	data, err := h.get(url + ".sha1")
+	if err != nil {
+		return ""
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 || len(fields[0]) != 40 {
+		return ""
+	}
+	return "sha1:" + fields[0]
+}
@@ -0,0 +1,50 @@
+package mcdist
+
+import "testing"
+
+func TestIsRelease(t *testing.T) {
+	tests := map[string]bool{
+		"1.21.1":        true,
+		"1.21":          true,
+		"26.1":          true,
+		"26.1.2":        true,
+		"24w14a":        false,
+		"1.21-pre1":     false,
+		"1.21.5-rc1":    false,
+		"26.1-snapshot": false,
+		"latest":        false,
+		"":              false,
+	}
+	for v, want := range tests {
+		if got := IsRelease(v); got != want {
+			t.Errorf("IsRelease(%q) = %v, want %v", v, got, want)
+		}
+	}
+}
+
+func TestNeoForgeVersions(t *testing.T) {
+	tests := []struct {
+		game, prefix, build string
+	}{
+		{"1.21.1", "21.1.", "21.1.72"},
+		{"1.21", "21.0.", "21.0.167"},
+		{"1.20.4", "20.4.", "20.4.237"},
+		{"26.1", "26.1.0.", "26.1.0.5-beta"},
+		{"26.1.2", "26.1.2.", "26.1.2.14"},
+	}
+	for _, tt := range tests {
+		if got := neoForgePrefix(tt.game); got != tt.prefix {
+			t.Errorf("neoForgePrefix(%q) = %q, want %q", tt.game, got, tt.prefix)
+		}
+		if got := neoForgeGameVersion(tt.build); got != tt.game {
+			t.Errorf("neoForgeGameVersion(%q) = %q, want %q", tt.build, got, tt.game)
+		}
+	}
+}
+
+func TestSnapshotRejectedForFlavors(t *testing.T) {
+	r := &releasesOnly{Resolver: &paperResolver{}, typ: Paper}
+	if _, err := r.Resolve("latest-snapshot", "latest"); err == nil {
+		t.Fatal("expected latest-snapshot to be rejected for paper")
+	}
+}
@@ -0,0 +1,387 @@
+package mcdist
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/archive"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/mojang"
+)
+
+// vanillaResolver uses the Mojang version manifest
+type vanillaResolver struct {
+	client *mojang.Client
+}
+
+func (r *vanillaResolver) Resolve(gameVersion, build string) (*Release, error) {
+	manifest, err := r.client.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	version, err := manifest.Resolve(gameVersion)
+	if err != nil {
+		return nil, err
+	}
+	server, err := r.client.ServerDownload(version)
+	if err != nil {
+		return nil, err
+	}
+	return &Release{Type: Vanilla, GameVersion: version.ID, URL: server.URL, Checksum: "sha1:" + server.SHA1}, nil
+}
+
+// paperResolver uses the PaperMC v2 downloads API
+type paperResolver struct {
+	http *httpClient
+	base string
+}
+
+func (r *paperResolver) Resolve(gameVersion, build string) (*Release, error) {
+	project := r.base + "/v2/projects/paper"
+
+	if isLatest(gameVersion) {
+		var p struct {
+			Versions []string `json:"versions"`
+		}
+		if err := r.http.getJSON(project, &p); err != nil {
+			return nil, err
+		}
+		if len(p.Versions) == 0 {
+			return nil, fmt.Errorf("paper lists no versions")
+		}
+		gameVersion = p.Versions[len(p.Versions)-1]
+	}
+
+	var builds struct {
+		Builds []struct {
+			Build     int    `json:"build"`
+			Channel   string `json:"channel"`
+			Downloads map[string]struct {
+				Name   string `json:"name"`
+				SHA256 string `json:"sha256"`
+			} `json:"downloads"`
+		} `json:"builds"`
+	}
+	if err := r.http.getJSON(fmt.Sprintf("%s/versions/%s/builds", project, gameVersion), &builds); err != nil {
+		return nil, err
+	}
+
+	// Builds are oldest first; prefer the newest stable ("default") one
+	chosen := -1
+	for i := len(builds.Builds) - 1; i >= 0; i-- {
+		b := builds.Builds[i]
+		if isLatest(build) {
+			if b.Channel == "default" {
+				chosen = i
+				break
+			}
+			if chosen < 0 {
+				chosen = i
+			}
+		} else if strconv.Itoa(b.Build) == build {
+			chosen = i
+			break
+		}
+	}
+	if chosen < 0 {
+		return nil, fmt.Errorf("paper build %s not found for %s", build, gameVersion)
+	}
+
+	b := builds.Builds[chosen]
+	app, ok := b.Downloads["application"]
+	if !ok {
+		return nil, fmt.Errorf("paper build %d has no server download", b.Build)
+	}
+	return &Release{
+		Type:        Paper,
+		GameVersion: gameVersion,
+		Build:       strconv.Itoa(b.Build),
+		URL:         fmt.Sprintf("%s/versions/%s/builds/%d/downloads/%s", project, gameVersion, b.Build, app.Name),
+		Checksum:    "sha256:" + app.SHA256,
+	}, nil
+}
+
+// purpurResolver uses the Purpur v2 API
+type purpurResolver struct {
+	http *httpClient
+	base string
+}
+
+func (r *purpurResolver) Resolve(gameVersion, build string) (*Release, error) {
+	project := r.base + "/v2/purpur"
+
+	if isLatest(gameVersion) {
+		var p struct {
+			Versions []string `json:"versions"`
+		}
+		if err := r.http.getJSON(project, &p); err != nil {
+			return nil, err
+		}
+		if len(p.Versions) == 0 {
+			return nil, fmt.Errorf("purpur lists no versions")
+		}
+		gameVersion = p.Versions[len(p.Versions)-1]
+	}
+
+	if isLatest(build) {
+		var v struct {
+			Builds struct {
+				Latest string `json:"latest"`
+			} `json:"builds"`
+		}
+		if err := r.http.getJSON(fmt.Sprintf("%s/%s", project, gameVersion), &v); err != nil {
+			return nil, err
+		}
+		build = v.Builds.Latest
+	}
+
+	var details struct {
+		MD5 string `json:"md5"`
+	}
+	if err := r.http.getJSON(fmt.Sprintf("%s/%s/%s", project, gameVersion, build), &details); err != nil {
+		return nil, err
+	}
+
+	rel := &Release{
+		Type:        Purpur,
+		GameVersion: gameVersion,
+		Build:       build,
+		URL:         fmt.Sprintf("%s/%s/%s/download", project, gameVersion, build),
+	}
+	if details.MD5 != "" {
+		rel.Checksum = "md5:" + details.MD5
+	}
+	return rel, nil
+}
+
+// fabricResolver uses the Fabric meta API; the download is the server launcher
+type fabricResolver struct {
+	http *httpClient
+	base string
+}
+
+type fabricVersion struct {
+	Version string `json:"version"`
+	Stable  bool   `json:"stable"`
+}
+
+func (r *fabricResolver) Resolve(gameVersion, build string) (*Release, error) {
+	if isLatest(gameVersion) {
+		var games []fabricVersion
+		if err := r.http.getJSON(r.base+"/v2/versions/game", &games); err != nil {
+			return nil, err
+		}
+		v, err := firstStable(games)
+		if err != nil {
+			return nil, fmt.Errorf("fabric: %w", err)
+		}
+		gameVersion = v
+	}
+
+	if isLatest(build) {
+		var loaders []struct {
+			Loader fabricVersion `json:"loader"`
+		}
+		if err := r.http.getJSON(fmt.Sprintf("%s/v2/versions/loader/%s", r.base, gameVersion), &loaders); err != nil {
+			return nil, err
+		}
+		var versions []fabricVersion
+		for _, l := range loaders {
+			versions = append(versions, l.Loader)
+		}
+		v, err := firstStable(versions)
+		if err != nil {
+			return nil, fmt.Errorf("fabric does not support %s: %w", gameVersion, err)
+		}
+		build = v
+	}
+
+	var installers []fabricVersion
+	if err := r.http.getJSON(r.base+"/v2/versions/installer", &installers); err != nil {
+		return nil, err
+	}
+	installer, err := firstStable(installers)
+	if err != nil {
+		return nil, fmt.Errorf("fabric: %w", err)
+	}
+
+	return &Release{
+		Type:        Fabric,
+		GameVersion: gameVersion,
+		Build:       build,
+		URL:         fmt.Sprintf("%s/v2/versions/loader/%s/%s/%s/server/jar", r.base, gameVersion, build, installer),
+	}, nil
+}
+
+// firstStable returns the first stable entry (lists are newest first)
+func firstStable(versions []fabricVersion) (string, error) {
+	for _, v := range versions {
+		if v.Stable {
+			return v.Version, nil
+		}
+	}
+	if len(versions) > 0 {
+		return versions[0].Version, nil
+	}
+	return "", fmt.Errorf("no versions available")
+}
+
+// forgeResolver uses Forge promotions and its maven repository
+type forgeResolver struct {
+	http       *httpClient
+	maven      string
+	promotions string
+}
+
+func (r *forgeResolver) Resolve(gameVersion, build string) (*Release, error) {
+	if isLatest(gameVersion) || isLatest(build) || strings.EqualFold(build, "recommended") {
+		var promos struct {
+			Promos map[string]string `json:"promos"`
+		}
+		if err := r.http.getJSON(r.promotions, &promos); err != nil {
+			return nil, err
+		}
+
+		if isLatest(gameVersion) {
+			newest := ""
+			for key := range promos.Promos {
+				mc, ok := strings.CutSuffix(key, "-latest")
+				if ok && (newest == "" || archive.CompareVersions(mc, newest) > 0) {
+					newest = mc
+				}
+			}
+			if newest == "" {
+				return nil, fmt.Errorf("forge promotions list no versions")
+			}
+			gameVersion = newest
+		}
+
+		if isLatest(build) || strings.EqualFold(build, "recommended") {
+			promo, ok := promos.Promos[gameVersion+"-recommended"]
+			if !ok || isLatest(build) {
+				promo, ok = promos.Promos[gameVersion+"-latest"]
+			}
+			if !ok {
+				return nil, fmt.Errorf("forge has no build for %s", gameVersion)
+			}
+			build = promo
+		}
+	}
+
+	full := gameVersion + "-" + build
+	url := fmt.Sprintf("%s/net/minecraftforge/forge/%s/forge-%s-installer.jar", r.maven, full, full)
+	return &Release{
+		Type:        Forge,
+		GameVersion: gameVersion,
+		Build:       build,
+		URL:         url,
+		Checksum:    r.http.mavenSHA1(url),
+		Installer:   true,
+	}, nil
+}
+
+// neoForgeResolver uses the NeoForged maven repository. NeoForge versions
+// encode the game version: 21.1.x is for 1.21.1, 21.0.x for 1.21 and
+// 26.1.0.x for 26.1.
+type neoForgeResolver struct {
+	http  *httpClient
+	maven string
+}
+
+func (r *neoForgeResolver) Resolve(gameVersion, build string) (*Release, error) {
+	if isLatest(build) {
+		versions, err := r.http.mavenVersions(r.maven + "/net/neoforged/neoforge/maven-metadata.xml")
+		if err != nil {
+			return nil, err
+		}
+
+		prefix := ""
+		if !isLatest(gameVersion) {
+			prefix = neoForgePrefix(gameVersion)
+		}
+		build = newestNeoForge(versions, prefix)
+		if build == "" {
+			return nil, fmt.Errorf("neoforge has no build for %s", gameVersion)
+		}
+	}
+	if isLatest(gameVersion) {
+		gameVersion = neoForgeGameVersion(build)
+	}
+
+	url := fmt.Sprintf("%s/net/neoforged/neoforge/%s/neoforge-%s-installer.jar", r.maven, build, build)
+	return &Release{
+		Type:        NeoForge,
+		GameVersion: gameVersion,
+		Build:       build,
+		URL:         url,
+		Checksum:    r.http.mavenSHA1(url),
+		Installer:   true,
+	}, nil
+}
+
+// neoForgePrefix maps a game version to its NeoForge version prefix. Up to
+// 1.21.x the leading "1." is dropped ("1.21.1" -> "21.1."); from the
+// year-based versions on the game version is kept whole with its hotfix
+// number ("26.1" -> "26.1.0.")
+func neoForgePrefix(gameVersion string) string {
+	parts, ok := releaseParts(gameVersion)
+	if !ok {
+		return gameVersion + "."
+	}
+	if parts[0] == 1 {
+		parts = parts[1:]
+		if len(parts) < 2 {
+			parts = append(parts, 0)
+		}
+	} else if len(parts) < 3 {
+		parts = append(parts, 0)
+	}
+	prefix := ""
+	for _, p := range parts {
+		prefix += strconv.Itoa(p) + "."
+	}
+	return prefix
+}
+
+// neoForgeGameVersion maps a NeoForge version back to its game version
+// ("21.1.72" -> "1.21.1", "26.1.0.5" -> "26.1")
+func neoForgeGameVersion(build string) string {
+	parts := strings.Split(strings.SplitN(build, "-", 2)[0], ".")
+	if len(parts) < 3 {
+		return build
+	}
+	if len(parts) > 3 {
+		// Year-based: <year>.<drop>.<hotfix>.<build>
+		if parts[2] == "0" {
+			return parts[0] + "." + parts[1]
+		}
+		return parts[0] + "." + parts[1] + "." + parts[2]
+	}
+	if parts[1] == "0" {
+		return "1." + parts[0]
+	}
+	return "1." + parts[0] + "." + parts[1]
+}
+
+// newestNeoForge picks the highest version with prefix, preferring non-beta releases
+func newestNeoForge(versions []string, prefix string) string {
+	var best, bestBeta string
+	for _, v := range versions {
+		if !strings.HasPrefix(v, prefix) {
+			continue
+		}
+		if strings.Contains(v, "-") {
+			if bestBeta == "" || archive.CompareVersions(v, bestBeta) > 0 {
+				bestBeta = v
+			}
+			continue
+		}
+		if best == "" || archive.CompareVersions(v, best) > 0 {
+			best = v
+		}
+	}
+	if best == "" {
+		return bestBeta
+	}
+	return best
+}
@@ -0,0 +1,158 @@
+package modrinth
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/config"
+)
+
+const apiBase = "https://api.modrinth.com"
+
+// Client handles Modrinth v2 API interactions
+type Client struct {
+	httpClient *http.Client
+	apiURL     string
+	token      string
+}
+
+// Project is a Modrinth project (mod, plugin, ...)
+type Project struct {
+	ID          string `json:"id"`
+	Slug        string `json:"slug"`
+	Title       string `json:"title"`
+	ProjectType string `json:"project_type"`
+}
+
+// Version is a published version of a project
+type Version struct {
+	ID            string       `json:"id"`
+	ProjectID     string       `json:"project_id"`
+	Name          string       `json:"name"`
+	VersionNumber string       `json:"version_number"`
+	VersionType   string       `json:"version_type"` // release, beta or alpha
+	DatePublished string       `json:"date_published"`
+	Loaders       []string     `json:"loaders"`
+	GameVersions  []string     `json:"game_versions"`
+	Files         []File       `json:"files"`
+	Dependencies  []Dependency `json:"dependencies"`
+}
+
+// File is a downloadable file of a version
+type File struct {
+	URL      string            `json:"url"`
+	Filename string            `json:"filename"`
+	Primary  bool              `json:"primary"`
+	Hashes   map[string]string `json:"hashes"`
+}
+
+// Dependency links a version to another project or version
+type Dependency struct {
+	ProjectID      string `json:"project_id"`
+	VersionID      string `json:"version_id"`
+	FileName       string `json:"file_name"`
+	DependencyType string `json:"dependency_type"` // required, optional, incompatible or embedded
+}
+
+// NewClient creates a Modrinth client; MODRINTH_API_URL points it at a mirror
+// and MODRINTH_API_TOKEN is sent when set
+func NewClient(cfg *config.Config) *Client {
+	return &Client{
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		apiURL: strings.TrimSuffix(cfg.GetString("MODRINTH_API_URL", apiBase), "/"),
+		token:  cfg.GetString("MODRINTH_API_TOKEN", ""),
+	}
+}
+
+// apiGet makes a GET request to the Modrinth API and decodes the JSON response
+func (c *Client) apiGet(path string, v interface{}) error {
+	req, err := http.NewRequest("GET", c.apiURL+path, nil)
+	if err != nil {
+		return err
+	}
+
+	// Modrinth asks clients to identify themselves
+	req.Header.Set("User-Agent", "kubelize/game-servers gamekeeper")
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("not found on Modrinth: %s", path)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("API request failed with status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// GetProject fetches a project by ID or slug
+func (c *Client) GetProject(idOrSlug string) (*Project, error) {
+	var p Project
+	if err := c.apiGet("/v2/project/"+url.PathEscape(idOrSlug), &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// GetVersion fetches a version by ID
+func (c *Client) GetVersion(id string) (*Version, error) {
+	var v Version
+	if err := c.apiGet("/v2/version/"+url.PathEscape(id), &v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// ProjectVersions lists a project's versions for any of the loaders and,
+// when gameVersion is set, that game version
+func (c *Client) ProjectVersions(idOrSlug string, loaders []string, gameVersion string) ([]Version, error) {
+	query := url.Values{}
+	if len(loaders) > 0 {
+		data, _ := json.Marshal(loaders)
+		query.Set("loaders", string(data))
+	}
+	if gameVersion != "" {
+		data, _ := json.Marshal([]string{gameVersion})
+		query.Set("game_versions", string(data))
+	}
+
+	var versions []Version
+	path := fmt.Sprintf("/v2/project/%s/version?%s", url.PathEscape(idOrSlug), query.Encode())
+	if err := c.apiGet(path, &versions); err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// PrimaryFile returns the version's primary file, or its first file
+func (v *Version) PrimaryFile() (*File, error) {
+	for i := range v.Files {
+		if v.Files[i].Primary {
+			return &v.Files[i], nil
+		}
+	}
+	if len(v.Files) > 0 {
+		return &v.Files[0], nil
+	}
+	return nil, fmt.Errorf("version %s has no files", v.ID)
+}
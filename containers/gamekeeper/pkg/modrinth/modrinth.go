@@ -0,0 +1,662 @@
+package modrinth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/archive"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/config"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/download"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/fsutil"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/output"
+)
+
+// Manifest tracks installed projects
+type Manifest struct {
+	SchemaVersion  int                        `json:"schemaVersion"`
+	LastCheckEpoch int64                      `json:"lastCheckEpoch"`
+	Projects       map[string]ManifestProject `json:"projects"`
+}
+
+// ManifestProject represents a project in the manifest
+type ManifestProject struct {
+	Slug string `json:"slug"`
+	// Reference is the configured reference; empty for dependencies
+	Reference    string           `json:"reference,omitempty"`
+	RequiredBy   []string         `json:"requiredBy,omitempty"`
+	Dependencies []string         `json:"dependencies,omitempty"`
+	Resolved     *ResolvedVersion `json:"resolved,omitempty"`
+	Installed    *InstalledFile   `json:"installed,omitempty"`
+}
+
+// ResolvedVersion represents resolved version info
+type ResolvedVersion struct {
+	VersionID     string   `json:"versionId"`
+	VersionNumber string   `json:"versionNumber"`
+	VersionType   string   `json:"versionType"`
+	DatePublished string   `json:"datePublished"`
+	FileName      string   `json:"fileName"`
+	DownloadURL   string   `json:"downloadUrl"`
+	SHA512        string   `json:"sha512,omitempty"`
+	Loaders       []string `json:"loaders"`
+	GameVersions  []string `json:"gameVersions"`
+}
+
+// InstalledFile represents installed file info
+type InstalledFile struct {
+	VersionID        string `json:"versionId"`
+	Path             string `json:"path"`
+	InstalledAtEpoch int64  `json:"installedAtEpoch"`
+}
+
+// Manager handles Modrinth project installation
+type Manager struct {
+	client         *Client
+	downloader     *download.Client
+	cfg            *config.Config
+	modsDir        string
+	stateDir       string
+	downloadsDir   string
+	filesDir       string
+	manifestPath   string
+	loaders        []string
+	gameVersion    string
+	releaseChannel string
+	autoUpdate     bool
+	failOnError    bool
+	prune          bool
+}
+
+// pending is a project waiting to be resolved: a configured reference or a
+// required dependency of another project
+type pending struct {
+	ref        string
+	projectID  string
+	versionID  string
+	requiredBy string
+}
+
+// NewManager creates a new Modrinth manager
+// modsDir is where the server loads mods or plugins from
+// stateDir holds the manifest and downloaded files
+// loaders and gameVersion filter which versions are compatible with the server
+func NewManager(cfg *config.Config, modsDir, stateDir string, loaders []string, gameVersion string) (*Manager, error) {
+	m := &Manager{
+		client:         NewClient(cfg),
+		downloader:     download.New(cfg),
+		cfg:            cfg,
+		modsDir:        modsDir,
+		stateDir:       stateDir,
+		downloadsDir:   filepath.Join(stateDir, "downloads"),
+		filesDir:       filepath.Join(stateDir, "files"),
+		manifestPath:   filepath.Join(stateDir, "manifest.json"),
+		loaders:        loaders,
+		gameVersion:    gameVersion,
+		releaseChannel: strings.ToLower(cfg.GetString("MINECRAFT_MODRINTH_RELEASE_CHANNEL", "release")),
+		autoUpdate:     cfg.GetBool("MINECRAFT_MODRINTH_AUTO_UPDATE", true),
+		failOnError:    cfg.GetBool("MINECRAFT_MODRINTH_FAIL_ON_ERROR", false),
+		prune:          cfg.GetBool("MINECRAFT_MODRINTH_PRUNE", false),
+	}
+
+	for _, dir := range []string{m.modsDir, m.stateDir, m.downloadsDir, m.filesDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory %s: %w", dir, err)
+		}
+	}
+
+	return m, nil
+}
+
+// InstallMods installs the configured projects and their required dependencies.
+// References are "slug", "slug:<version id or number>" or "slug@<constraints>"
+// such as "slug@>=5.3,<6".
+func (m *Manager) InstallMods(refs string) error {
+	if refs == "" {
+		output.Info("No Modrinth projects configured")
+		return nil
+	}
+
+	manifest := m.loadManifest()
+	desired := make(map[string]bool)
+	incompatible := make(map[string]string)
+	errors := 0
+
+	// Configured references are queued first, so their versions win over
+	// whatever a dependency would have picked
+	var queue []pending
+	for _, ref := range expandRefs(refs) {
+		queue = append(queue, pending{ref: ref})
+	}
+
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+
+		if p.ref != "" {
+			output.Step(fmt.Sprintf("Processing project: %s", p.ref))
+		} else {
+			dep := p.projectID
+			if dep == "" {
+				dep = "version " + p.versionID
+			}
+			output.Step(fmt.Sprintf("Processing dependency: %s (required by %s)", m.label(manifest, dep), m.label(manifest, p.requiredBy)))
+		}
+
+		projectID, deps, skip, err := m.process(p, &manifest, desired, incompatible)
+		if err != nil {
+			output.Warning(err.Error())
+			errors++
+			continue
+		}
+		if skip {
+			continue
+		}
+
+		desired[projectID] = true
+		queue = append(queue, deps...)
+	}
+
+	for id, by := range incompatible {
+		if desired[id] {
+			output.Warning(fmt.Sprintf("%s is marked incompatible with %s", m.label(manifest, id), m.label(manifest, by)))
+		}
+	}
+
+	// A failed lookup must not look like a project that was removed from the config
+	if m.prune && errors == 0 {
+		m.pruneProjects(&manifest, desired)
+	}
+
+	m.saveManifest(&manifest)
+
+	if errors > 0 && m.failOnError {
+		return fmt.Errorf("%d Modrinth project(s) failed to install", errors)
+	}
+
+	return nil
+}
+
+// process resolves and installs one queued project. It returns the project ID
+// and its required dependencies, or skip when the project was already handled.
+func (m *Manager) process(p pending, manifest *Manifest, desired map[string]bool, incompatible map[string]string) (string, []pending, bool, error) {
+	if p.projectID != "" && desired[p.projectID] {
+		m.addRequiredBy(manifest, p.projectID, p.requiredBy)
+		output.SuccessWithMessage("already required")
+		return "", nil, true, nil
+	}
+
+	// With auto-update off, whatever the manifest recorded is reinstalled as-is
+	if !m.autoUpdate {
+		if id, ok := m.findInstalled(manifest, p); ok {
+			if desired[id] {
+				m.addRequiredBy(manifest, id, p.requiredBy)
+				output.SuccessWithMessage("already required")
+				return "", nil, true, nil
+			}
+			if err := m.link(id, manifest.Projects[id]); err == nil {
+				m.addRequiredBy(manifest, id, p.requiredBy)
+				output.SuccessWithMessage(fmt.Sprintf("%s (auto-update disabled)", manifest.Projects[id].Resolved.VersionNumber))
+				var deps []pending
+				for _, dep := range manifest.Projects[id].Dependencies {
+					deps = append(deps, pending{projectID: dep, requiredBy: id})
+				}
+				return id, deps, false, nil
+			}
+		}
+	}
+
+	var slug, pin, constraint string
+	if p.ref != "" {
+		var err error
+		slug, pin, constraint, err = parseRef(p.ref)
+		if err != nil {
+			return "", nil, false, err
+		}
+	} else {
+		slug, pin = p.projectID, p.versionID
+	}
+
+	// A dependency may name only a version
+	if slug == "" && pin != "" {
+		v, err := m.client.GetVersion(pin)
+		if err != nil {
+			return "", nil, false, fmt.Errorf("could not resolve dependency version %s: %v", pin, err)
+		}
+		slug = v.ProjectID
+	}
+
+	project, err := m.client.GetProject(slug)
+	if err != nil {
+		return "", nil, false, fmt.Errorf("could not find project '%s': %v", slug, err)
+	}
+	m.addDependency(manifest, p.requiredBy, project.ID)
+	if desired[project.ID] {
+		m.addRequiredBy(manifest, project.ID, p.requiredBy)
+		output.SuccessWithMessage("already required")
+		return "", nil, true, nil
+	}
+
+	var version *Version
+	if p.ref == "" && pin != "" {
+		// The dependency names an exact version
+		version, err = m.client.GetVersion(pin)
+	} else {
+		version, err = m.resolveVersion(project, pin, constraint)
+	}
+	if err != nil {
+		return "", nil, false, fmt.Errorf("could not resolve %s: %v", project.Slug, err)
+	}
+
+	entry := manifest.Projects[project.ID]
+	entry.Slug = project.Slug
+	entry.Reference = p.ref
+	entry.Dependencies = nil
+	var deps []pending
+	for _, dep := range version.Dependencies {
+		switch dep.DependencyType {
+		case "required":
+			if dep.ProjectID == "" && dep.VersionID == "" {
+				output.Warning(fmt.Sprintf("%s requires %s, which is not on Modrinth", project.Slug, dep.FileName))
+				continue
+			}
+			deps = append(deps, pending{projectID: dep.ProjectID, versionID: dep.VersionID, requiredBy: project.ID})
+		case "incompatible":
+			if dep.ProjectID != "" {
+				incompatible[dep.ProjectID] = project.ID
+			}
+		}
+	}
+	manifest.Projects[project.ID] = entry
+	m.addRequiredBy(manifest, project.ID, p.requiredBy)
+
+	if err := m.install(project, version, manifest); err != nil {
+		if manifest.Projects[project.ID].Installed == nil {
+			delete(manifest.Projects, project.ID)
+		}
+		return "", nil, false, fmt.Errorf("failed to install %s: %v", project.Slug, err)
+	}
+	return project.ID, deps, false, nil
+}
+
+// resolveVersion picks the newest compatible version allowed by the release
+// channel, or the pinned version (by ID or version number)
+func (m *Manager) resolveVersion(project *Project, pin, constraint string) (*Version, error) {
+	gameVersion := m.gameVersion
+	if pin != "" {
+		// An explicit pin is trusted to work with this game version
+		gameVersion = ""
+	}
+
+	versions, err := m.client.ProjectVersions(project.ID, m.loaders, gameVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	var best *Version
+	for i := range versions {
+		v := &versions[i]
+		if pin != "" {
+			if v.ID == pin || v.VersionNumber == pin {
+				return v, nil
+			}
+			continue
+		}
+		if !m.channelAllows(v.VersionType) {
+			continue
+		}
+		if constraint != "" && !matchesConstraint(v.VersionNumber, constraint) {
+			continue
+		}
+		if best == nil || v.DatePublished > best.DatePublished {
+			best = v
+		}
+	}
+
+	if best == nil {
+		if pin != "" {
+			return nil, fmt.Errorf("version %s not found for %s", pin, strings.Join(m.loaders, "/"))
+		}
+		return nil, fmt.Errorf("no %s version for %s %s", m.releaseChannel, strings.Join(m.loaders, "/"), m.gameVersion)
+	}
+	return best, nil
+}
+
+// install downloads a version's primary file, verifies its sha512 and links it
+// into the mods directory
+func (m *Manager) install(project *Project, version *Version, manifest *Manifest) error {
+	file, err := version.PrimaryFile()
+	if err != nil {
+		return err
+	}
+
+	entry := manifest.Projects[project.ID]
+	entry.Resolved = &ResolvedVersion{
+		VersionID:     version.ID,
+		VersionNumber: version.VersionNumber,
+		VersionType:   version.VersionType,
+		DatePublished: version.DatePublished,
+		FileName:      file.Filename,
+		DownloadURL:   file.URL,
+		SHA512:        file.Hashes["sha512"],
+		Loaders:       version.Loaders,
+		GameVersions:  version.GameVersions,
+	}
+
+	if entry.Installed != nil && entry.Installed.VersionID == version.ID {
+		if err := m.link(project.ID, entry); err == nil {
+			manifest.Projects[project.ID] = entry
+			output.SuccessWithMessage(fmt.Sprintf("%s already installed", version.VersionNumber))
+			return nil
+		}
+	}
+
+	destDir := filepath.Join(m.filesDir, project.ID, version.ID)
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return err
+	}
+	destPath := filepath.Join(destDir, file.Filename)
+
+	if _, err := os.Stat(destPath); err != nil {
+		var sums []download.Checksum
+		for _, algo := range []string{"sha512", "sha1"} {
+			if value := file.Hashes[algo]; value != "" {
+				sums = append(sums, download.Checksum{Algo: algo, Value: value})
+			}
+		}
+
+		// Partial downloads are kept and resumed; tmpPath only appears once verified
+		tmpPath := filepath.Join(m.downloadsDir, version.ID+".tmp")
+		if err := m.downloader.Get(file.URL, tmpPath, download.Options{Checksums: sums}); err != nil {
+			return err
+		}
+		if err := os.Rename(tmpPath, destPath); err != nil {
+			os.Remove(tmpPath)
+			return err
+		}
+	}
+
+	// Drop the previously installed version
+	if entry.Installed != nil {
+		if entry.Installed.Path != "" {
+			os.Remove(filepath.Join(m.modsDir, entry.Installed.Path))
+		}
+		if entry.Installed.VersionID != version.ID {
+			os.RemoveAll(filepath.Join(m.filesDir, project.ID, entry.Installed.VersionID))
+		}
+	}
+
+	entry.Installed = &InstalledFile{
+		VersionID:        version.ID,
+		Path:             fmt.Sprintf("mr-%s-%s", project.ID, safeFilename(file.Filename)),
+		InstalledAtEpoch: time.Now().Unix(),
+	}
+	if err := m.link(project.ID, entry); err != nil {
+		return err
+	}
+	manifest.Projects[project.ID] = entry
+
+	output.SuccessWithMessage(version.VersionNumber)
+	return nil
+}
+
+// link makes the stored file of an installed project visible in the mods directory
+func (m *Manager) link(projectID string, entry ManifestProject) error {
+	if entry.Installed == nil || entry.Resolved == nil {
+		return fmt.Errorf("%s is not installed", projectID)
+	}
+
+	src := filepath.Join(m.filesDir, projectID, entry.Installed.VersionID, entry.Resolved.FileName)
+	if _, err := os.Stat(src); err != nil {
+		return fmt.Errorf("%s is missing", entry.Resolved.FileName)
+	}
+
+	visible := filepath.Join(m.modsDir, entry.Installed.Path)
+	if target, err := os.Readlink(visible); err == nil && target == src {
+		return nil
+	}
+
+	os.Remove(visible)
+	if err := os.Symlink(src, visible); err != nil {
+		return fsutil.CopyFile(src, visible)
+	}
+	return nil
+}
+
+// findInstalled looks up the manifest entry for a queued project
+func (m *Manager) findInstalled(manifest *Manifest, p pending) (string, bool) {
+	for id, entry := range manifest.Projects {
+		if entry.Installed == nil || entry.Resolved == nil {
+			continue
+		}
+		if p.ref != "" && entry.Reference == p.ref {
+			return id, true
+		}
+		if p.ref == "" && (id == p.projectID || (p.versionID != "" && entry.Installed.VersionID == p.versionID)) {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// addRequiredBy records that parent needs projectID
+func (m *Manager) addRequiredBy(manifest *Manifest, projectID, parent string) {
+	if parent == "" {
+		return
+	}
+	entry := manifest.Projects[projectID]
+	for _, existing := range entry.RequiredBy {
+		if existing == parent {
+			return
+		}
+	}
+	entry.RequiredBy = append(entry.RequiredBy, parent)
+	manifest.Projects[projectID] = entry
+}
+
+// addDependency records that parent needs projectID, so the dependency is
+// kept when the manifest is reinstalled without resolving anything
+func (m *Manager) addDependency(manifest *Manifest, parent, projectID string) {
+	if parent == "" {
+		return
+	}
+	entry := manifest.Projects[parent]
+	for _, existing := range entry.Dependencies {
+		if existing == projectID {
+			return
+		}
+	}
+	entry.Dependencies = append(entry.Dependencies, projectID)
+	manifest.Projects[parent] = entry
+}
+
+// label names a project by slug when the manifest knows it
+func (m *Manager) label(manifest Manifest, projectID string) string {
+	if entry, ok := manifest.Projects[projectID]; ok && entry.Slug != "" {
+		return entry.Slug
+	}
+	return projectID
+}
+
+// channelAllows reports whether a version type is allowed by the release channel
+func (m *Manager) channelAllows(versionType string) bool {
+	switch m.releaseChannel {
+	case "alpha", "any":
+		return true
+	case "beta":
+		return versionType == "release" || versionType == "beta"
+	default:
+		return versionType == "release"
+	}
+}
+
+// loadManifest loads or creates the manifest
+func (m *Manager) loadManifest() Manifest {
+	manifest := Manifest{
+		SchemaVersion: 1,
+		Projects:      make(map[string]ManifestProject),
+	}
+
+	data, err := os.ReadFile(m.manifestPath)
+	if err != nil {
+		return manifest
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return manifest
+	}
+	if manifest.Projects == nil {
+		manifest.Projects = make(map[string]ManifestProject)
+	}
+
+	// Requirements are rebuilt on every run
+	for id, entry := range manifest.Projects {
+		entry.RequiredBy = nil
+		manifest.Projects[id] = entry
+	}
+	return manifest
+}
+
+// saveManifest saves the manifest
+func (m *Manager) saveManifest(manifest *Manifest) {
+	manifest.LastCheckEpoch = time.Now().Unix()
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return
+	}
+
+	os.WriteFile(m.manifestPath, data, 0644)
+}
+
+// pruneProjects removes projects that are neither configured nor required
+func (m *Manager) pruneProjects(manifest *Manifest, desired map[string]bool) {
+	for id, entry := range manifest.Projects {
+		if desired[id] {
+			continue
+		}
+		if entry.Installed != nil && entry.Installed.Path != "" {
+			os.Remove(filepath.Join(m.modsDir, entry.Installed.Path))
+		}
+		os.RemoveAll(filepath.Join(m.filesDir, id))
+		delete(manifest.Projects, id)
+	}
+}
+
+// Helper functions
+
+// expandRefs expands project references (including @file references)
+func expandRefs(input string) []string {
+	var refs []string
+
+	for _, line := range strings.Split(input, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Handle @file references
+		if strings.HasPrefix(line, "@") {
+			if data, err := os.ReadFile(strings.TrimPrefix(line, "@")); err == nil {
+				for _, fline := range strings.Split(string(data), "\n") {
+					fline = strings.TrimSpace(fline)
+					if fline != "" && !strings.HasPrefix(fline, "#") {
+						refs = append(refs, strings.Fields(fline)...)
+					}
+				}
+			}
+			continue
+		}
+
+		refs = append(refs, strings.Fields(line)...)
+	}
+
+	return refs
+}
+
+func parseRef(ref string) (slug, pin, constraint string, err error) {
+	// Format: slug, slug:version, slug@constraints
+	if i := strings.IndexAny(ref, ":@"); i >= 0 {
+		slug = ref[:i]
+		if ref[i] == ':' {
+			pin = ref[i+1:]
+		} else {
+			constraint = ref[i+1:]
+		}
+	} else {
+		slug = ref
+	}
+
+	if slug == "" || (strings.ContainsAny(ref, ":@") && pin == "" && constraint == "") {
+		return "", "", "", fmt.Errorf("invalid project reference: %s", ref)
+	}
+	return slug, pin, constraint, nil
+}
+
+// matchesConstraint checks a version number against comma-separated
+// constraints like ">=5.3,<6". A bare value matches that version and its
+// point releases, so "5.3" matches "5.3.1".
+func matchesConstraint(version, constraint string) bool {
+	version = versionCore(version)
+	for _, c := range strings.Split(constraint, ",") {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+
+		op := ""
+		for _, candidate := range []string{">=", "<=", "!=", ">", "<", "="} {
+			if strings.HasPrefix(c, candidate) {
+				op = candidate
+				break
+			}
+		}
+		want := strings.TrimPrefix(strings.TrimSpace(strings.TrimPrefix(c, op)), "v")
+		cmp := archive.CompareVersions(version, want)
+
+		var ok bool
+		switch op {
+		case ">=":
+			ok = cmp >= 0
+		case "<=":
+			ok = cmp <= 0
+		case ">":
+			ok = cmp > 0
+		case "<":
+			ok = cmp < 0
+		case "!=":
+			ok = cmp != 0
+		case "=":
+			ok = cmp == 0
+		default:
+			ok = version == want || strings.HasPrefix(version, want+".") || strings.HasPrefix(version, want+"-") || strings.HasPrefix(version, want+"+")
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// versionCore strips the decorations projects add to version numbers:
+// "v0.13.0", "mc1.21.1-0.13.0" and "0.13.0+1.21.1" all become "0.13.0"
+func versionCore(version string) string {
+	version = strings.TrimPrefix(version, "v")
+	if strings.HasPrefix(version, "mc") {
+		if i := strings.Index(version, "-"); i >= 0 {
+			version = version[i+1:]
+		}
+	}
+	if i := strings.Index(version, "+"); i >= 0 {
+		version = version[:i]
+	}
+	return version
+}
+
+func safeFilename(name string) string {
+	re := regexp.MustCompile(`[^A-Za-z0-9._-]`)
+	return re.ReplaceAllString(strings.ReplaceAll(name, " ", "_"), "_")
+}
@@ -0,0 +1,118 @@
+package mojang
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/config"
+)
+
+const manifestURL = "https://piston-meta.mojang.com/mc/game/version_manifest_v2.json"
+
+// Client reads the Minecraft version manifest
+type Client struct {
+	httpClient  *http.Client
+	manifestURL string
+	profileURL  string
+}
+
+// Manifest is the version manifest
+type Manifest struct {
+	Latest struct {
+		Release  string `json:"release"`
+		Snapshot string `json:"snapshot"`
+	} `json:"latest"`
+	Versions []Version `json:"versions"`
+}
+
+// Version is a manifest entry
+type Version struct {
+	ID          string `json:"id"`
+	Type        string `json:"type"`
+	URL         string `json:"url"`
+	ReleaseTime string `json:"releaseTime"`
+}
+
+// Download is a file published for a version
+type Download struct {
+	URL  string `json:"url"`
+	SHA1 string `json:"sha1"`
+	Size int64  `json:"size"`
+}
+
+// NewClient creates a client; MINECRAFT_MANIFEST_URL and
+// MINECRAFT_PROFILE_API_URL point it at mirrors
+func NewClient(cfg *config.Config) *Client {
+	return &Client{
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		manifestURL: cfg.GetString("MINECRAFT_MANIFEST_URL", manifestURL),
+		profileURL:  cfg.GetString("MINECRAFT_PROFILE_API_URL", profileURL),
+	}
+}
+
+// Manifest fetches the version manifest
+func (c *Client) Manifest() (*Manifest, error) {
+	var m Manifest
+	if err := c.getJSON(c.manifestURL, &m); err != nil {
+		return nil, fmt.Errorf("failed to fetch version manifest: %w", err)
+	}
+	return &m, nil
+}
+
+// Resolve finds a version by ID, or "latest" / "latest-snapshot"
+func (m *Manifest) Resolve(version string) (*Version, error) {
+	id := version
+	switch strings.ToLower(version) {
+	case "", "latest", "release", "latest-release":
+		id = m.Latest.Release
+	case "latest-snapshot", "snapshot":
+		id = m.Latest.Snapshot
+	}
+
+	for i := range m.Versions {
+		if m.Versions[i].ID == id {
+			return &m.Versions[i], nil
+		}
+	}
+	return nil, fmt.Errorf("minecraft version %s not found in manifest", version)
+}
+
+// ServerDownload returns the dedicated server jar for a version
+func (c *Client) ServerDownload(v *Version) (*Download, error) {
+	var details struct {
+		Downloads map[string]Download `json:"downloads"`
+	}
+	if err := c.getJSON(v.URL, &details); err != nil {
+		return nil, fmt.Errorf("failed to fetch details for %s: %w", v.ID, err)
+	}
+
+	server, ok := details.Downloads["server"]
+	if !ok || server.URL == "" {
+		return nil, fmt.Errorf("minecraft %s has no server download", v.ID)
+	}
+	return &server, nil
+}
+
+func (c *Client) getJSON(url string, v interface{}) error {
+	resp, err := c.httpClient.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("request failed with status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,68 @@
+package mojang
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const profileURL = "https://api.mojang.com/users/profiles/minecraft"
+
+// ErrUnknownPlayer is returned when the profile API has no such player
+var ErrUnknownPlayer = errors.New("unknown player")
+
+// LookupUUID resolves an online-mode player name to its dashed UUID through the
+// profile API (MINECRAFT_PROFILE_API_URL, queried as <url>/<name>)
+func (c *Client) LookupUUID(name string) (string, error) {
+	resp, err := c.httpClient.Get(strings.TrimSuffix(c.profileURL, "/") + "/" + url.PathEscape(name))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNoContent, http.StatusNotFound:
+		return "", fmt.Errorf("%w: %s", ErrUnknownPlayer, name)
+	default:
+		return "", fmt.Errorf("profile lookup for %s failed with status %d", name, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	var profile struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return "", fmt.Errorf("invalid profile for %s: %w", name, err)
+	}
+
+	id := strings.ReplaceAll(profile.ID, "-", "")
+	if len(id) != 32 {
+		return "", fmt.Errorf("invalid UUID for %s: %q", name, profile.ID)
+	}
+	return dashUUID(id), nil
+}
+
+// OfflineUUID returns the UUID an offline-mode server assigns to a name:
+// a version 3 UUID of "OfflinePlayer:<name>", as Java's UUID.nameUUIDFromBytes
+func OfflineUUID(name string) string {
+	sum := md5.Sum([]byte("OfflinePlayer:" + name))
+	sum[6] = sum[6]&0x0f | 0x30
+	sum[8] = sum[8]&0x3f | 0x80
+	return dashUUID(hex.EncodeToString(sum[:]))
+}
+
+func dashUUID(id string) string {
+	id = strings.ToLower(id)
+	return id[0:8] + "-" + id[8:12] + "-" + id[12:16] + "-" + id[16:20] + "-" + id[20:32]
+}
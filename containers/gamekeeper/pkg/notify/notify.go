@@ -0,0 +1,70 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/config"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/output"
+)
+
+// Notifier publishes events that need an operator's attention
+type Notifier struct {
+	webhookURL string
+	httpClient *http.Client
+	source     string
+}
+
+// New creates a notifier; events are always logged and additionally posted
+// to NOTIFY_WEBHOOK_URL when it is set
+func New(cfg *config.Config) *Notifier {
+	source, _ := os.Hostname()
+
+	return &Notifier{
+		webhookURL: cfg.GetString("NOTIFY_WEBHOOK_URL", ""),
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		source: cfg.GetString("NOTIFY_SOURCE", source),
+	}
+}
+
+// Event logs a structured event and forwards it to the webhook
+func (n *Notifier) Event(name, message string, fields map[string]string) {
+	output.Event(name, message, fields)
+
+	if n.webhookURL == "" {
+		return
+	}
+	if err := n.post(name, message, fields); err != nil {
+		output.Warning(fmt.Sprintf("failed to send notification: %v", err))
+	}
+}
+
+// post sends the event as JSON; "text" makes it readable by chat webhooks
+func (n *Notifier) post(name, message string, fields map[string]string) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"event":  name,
+		"source": n.source,
+		"text":   fmt.Sprintf("[%s] %s", n.source, message),
+		"fields": fields,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := n.httpClient.Post(n.webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
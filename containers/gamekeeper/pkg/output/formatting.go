@@ -1,7 +1,10 @@
 package output
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"time"
 )
 
 // ANSI color codes
@@ -59,3 +62,41 @@ func Launch(message string) {
 	fmt.Printf("%s%s🚀 %s%s\n", Bold, Green, message, Reset)
 	fmt.Println()
 }
+
+// Event prints a single-line JSON event, for log collectors and alerting
+func Event(name, message string, fields map[string]string) {
+	event := map[string]string{
+		"time":    time.Now().UTC().Format(time.RFC3339),
+		"event":   name,
+		"message": message,
+	}
+	for k, v := range fields {
+		event[k] = v
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	fmt.Fprintln(os.Stdout, string(data))
+}
+
+// Progress returns a callback that prints completion in 10% steps on the
+// current Step line, which keeps container logs readable
+func Progress() func(done, total int64) {
+	last := -1
+	return func(done, total int64) {
+		if total <= 0 {
+			return
+		}
+		pct := int(done * 100 / total)
+		if pct > 100 {
+			pct = 100
+		}
+		if pct/10 == last/10 && last >= 0 {
+			return
+		}
+		last = pct
+		fmt.Printf("%s%d%%%s ", Dim, pct/10*10, Reset)
+	}
+}
@@ -0,0 +1,116 @@
+package rcon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// Packet types of the Source RCON protocol (also used by Minecraft)
+const (
+	packetResponse = 0
+	packetCommand  = 2
+	packetAuth     = 3
+)
+
+// Client is a connection to a server's RCON port
+type Client struct {
+	conn    net.Conn
+	timeout time.Duration
+	nextID  int32
+}
+
+// Dial connects to addr and authenticates with password
+func Dial(addr, password string, timeout time.Duration) (*Client, error) {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{conn: conn, timeout: timeout, nextID: 1}
+	id, err := c.send(packetAuth, password)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// Some servers send an empty response before the auth result
+	for {
+		respID, typ, _, err := c.read()
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("rcon authentication failed: %w", err)
+		}
+		if respID == -1 {
+			conn.Close()
+			return nil, fmt.Errorf("rcon authentication failed: wrong password")
+		}
+		if respID == id && typ == packetCommand {
+			return c, nil
+		}
+	}
+}
+
+// Command runs a console command and returns its output
+func (c *Client) Command(cmd string) (string, error) {
+	id, err := c.send(packetCommand, cmd)
+	if err != nil {
+		return "", err
+	}
+	for {
+		respID, typ, body, err := c.read()
+		if err != nil {
+			return "", err
+		}
+		if respID == id && typ == packetResponse {
+			return body, nil
+		}
+	}
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Client) send(typ int32, body string) (int32, error) {
+	id := c.nextID
+	c.nextID++
+
+	// Length covers id, type, body and the two terminating NULs
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, int32(4+4+len(body)+2))
+	binary.Write(&buf, binary.LittleEndian, id)
+	binary.Write(&buf, binary.LittleEndian, typ)
+	buf.WriteString(body)
+	buf.Write([]byte{0, 0})
+
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+	if _, err := c.conn.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (c *Client) read() (id, typ int32, body string, err error) {
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+
+	var length int32
+	if err := binary.Read(c.conn, binary.LittleEndian, &length); err != nil {
+		return 0, 0, "", err
+	}
+	if length < 10 || length > 1<<20 {
+		return 0, 0, "", fmt.Errorf("invalid rcon packet length %d", length)
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(c.conn, data); err != nil {
+		return 0, 0, "", err
+	}
+	id = int32(binary.LittleEndian.Uint32(data[0:4]))
+	typ = int32(binary.LittleEndian.Uint32(data[4:8]))
+	return id, typ, string(bytes.TrimRight(data[8:], "\x00")), nil
+}
@@ -12,9 +12,13 @@ import (
 // StartServerWithTmux runs the server in a tmux session with output piped to stdout
 // and gotty attached for web console access
 func StartServerWithTmux(port, sessionName, command string, args []string, workdir string) error {
-	// Build the command string
-	fullCmd := command + " " + strings.Join(args, " ")
-	
+	// Build the command string; tmux runs it with the shell, so each
+	// argument is quoted to reach the server as a single token
+	fullCmd := shellQuote(command)
+	for _, arg := range args {
+		fullCmd += " " + shellQuote(arg)
+	}
+
 	// Create log file for tmux output
 	logFile := filepath.Join(workdir, "console.log")
 	
@@ -102,3 +106,39 @@ func tailLogToStdout(logFile string) {
 		fmt.Print(line)
 	}
 }
+
+// SendCommand types a command into the server console of a tmux session
+func SendCommand(sessionName, command string) error {
+	if err := exec.Command("tmux", "send-keys", "-t", sessionName, "-l", command).Run(); err != nil {
+		return fmt.Errorf("failed to send %q to the console: %w", command, err)
+	}
+	return exec.Command("tmux", "send-keys", "-t", sessionName, "Enter").Run()
+}
+
+// SendInterrupt sends Ctrl-C to the server in a tmux session
+func SendInterrupt(sessionName string) error {
+	if err := exec.Command("tmux", "send-keys", "-t", sessionName, "C-c").Run(); err != nil {
+		return fmt.Errorf("failed to interrupt the server: %w", err)
+	}
+	return nil
+}
+
+// SessionRunning reports whether the tmux session still exists
+func SessionRunning(sessionName string) bool {
+	return exec.Command("tmux", "has-session", "-t", sessionName).Run() == nil
+}
+
+// KillSession ends the tmux session and the server in it
+func KillSession(sessionName string) error {
+	return exec.Command("tmux", "kill-session", "-t", sessionName).Run()
+}
+
+// shellQuote quotes s for sh unless it only has characters that are safe as is
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./=:,+@%", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
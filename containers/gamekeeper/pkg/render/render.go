@@ -0,0 +1,213 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Renderer renders the gomplate templates shipped in the game images. It
+// implements the subset of gomplate they use: getenv, datasource (and its ds
+// alias), datasourceExists, default and toJSON.
+type Renderer struct {
+	values map[string]interface{}
+	files  map[string]string
+	data   map[string]interface{}
+}
+
+// New creates a renderer with the "config" datasource backed by cfg and the
+// "password" datasource backed by the chart's password secret
+// (SERVER_PASSWORD_FILE)
+func New(cfg *config.Config) *Renderer {
+	r := &Renderer{
+		values: cfg.Values(),
+		files:  make(map[string]string),
+		data:   make(map[string]interface{}),
+	}
+	r.Set("config", r.values)
+	r.AddFile("password", cfg.GetString("SERVER_PASSWORD_FILE", "/home/kubelize/config-data/serverpassword.yaml"))
+	return r
+}
+
+// AddFile defines a datasource read from a YAML or JSON file, like gomplate's
+// -d alias=path. The file is only read when a template uses it.
+func (r *Renderer) AddFile(alias, path string) {
+	delete(r.data, alias)
+	r.files[alias] = path
+}
+
+// Set defines a datasource with in-memory data
+func (r *Renderer) Set(alias string, data interface{}) {
+	delete(r.files, alias)
+	r.data[alias] = data
+}
+
+// RenderFile renders the template at path
+func (r *Renderer) RenderFile(path string) ([]byte, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
+	}
+	return r.Render(filepath.Base(path), string(text))
+}
+
+// Render executes a template
+func (r *Renderer) Render(name, text string) ([]byte, error) {
+	funcs := template.FuncMap{
+		"datasource":       r.datasource,
+		"ds":               r.datasource,
+		"datasourceExists": r.datasourceExists,
+		"getenv":           r.getenv,
+		"default":          defaultValue,
+		"toJSON":           toJSON,
+		"orEmpty":          orEmpty,
+	}
+
+	tmpl, err := template.New(name).Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", name, err)
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			emptyMissing(t.Tree, t.Tree.Root)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		return nil, fmt.Errorf("failed to render template %s: %w", name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// datasource returns the data of a datasource, reading its file on first use
+func (r *Renderer) datasource(alias string) (interface{}, error) {
+	if data, ok := r.data[alias]; ok {
+		return data, nil
+	}
+	path, ok := r.files[alias]
+	if !ok {
+		return nil, fmt.Errorf("undefined datasource %q", alias)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("datasource %q: %w", alias, err)
+	}
+	// YAML is a superset of JSON, so both parse here
+	var data interface{}
+	if err := yaml.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("datasource %q: failed to parse %s: %w", alias, path, err)
+	}
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	r.data[alias] = data
+	return data, nil
+}
+
+// datasourceExists reports whether a datasource is defined and, for files, readable
+func (r *Renderer) datasourceExists(alias string) bool {
+	if _, ok := r.data[alias]; ok {
+		return true
+	}
+	path, ok := r.files[alias]
+	if !ok {
+		return false
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// getenv returns an environment variable, falling back to the config value of
+// the same name and then to the optional default
+func (r *Renderer) getenv(key string, def ...string) string {
+	if v, ok := r.values[key]; ok && v != nil && v != "" {
+		return fmt.Sprint(v)
+	}
+	if len(def) > 0 {
+		return def[0]
+	}
+	return ""
+}
+
+// emptyMissing pipes every printed action through orEmpty, so a key missing
+// from a datasource prints as "" rather than "<no value>"
+func emptyMissing(tree *parse.Tree, node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			emptyMissing(tree, child)
+		}
+	case *parse.ActionNode:
+		if len(n.Pipe.Decl) == 0 {
+			ident := parse.NewIdentifier("orEmpty").SetTree(tree).SetPos(n.Pos)
+			n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{
+				NodeType: parse.NodeCommand,
+				Pos:      n.Pos,
+				Args:     []parse.Node{ident},
+			})
+		}
+	case *parse.IfNode:
+		emptyMissing(tree, n.List)
+		emptyMissing(tree, n.ElseList)
+	case *parse.RangeNode:
+		emptyMissing(tree, n.List)
+		emptyMissing(tree, n.ElseList)
+	case *parse.WithNode:
+		emptyMissing(tree, n.List)
+		emptyMissing(tree, n.ElseList)
+	}
+}
+
+func orEmpty(v ...interface{}) interface{} {
+	if len(v) == 0 || v[0] == nil {
+		return ""
+	}
+	return v[0]
+}
+
+// toJSON encodes a value as JSON, like gomplate's toJSON; piping a string
+// through it yields a quoted, escaped JSON string
+func toJSON(v interface{}) (string, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+	return string(bytes.TrimSuffix(buf.Bytes(), []byte("\n"))), nil
+}
+
+// defaultValue returns def when value is missing or empty, like gomplate's default
+func defaultValue(def interface{}, value ...interface{}) interface{} {
+	if len(value) == 0 || isEmpty(value[0]) {
+		return def
+	}
+	return value[0]
+}
+
+func isEmpty(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return rv.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return rv.IsNil()
+	}
+	return rv.IsZero()
+}
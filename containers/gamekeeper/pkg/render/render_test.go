@@ -0,0 +1,39 @@
+package render
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/config"
+)
+
+func TestRenderEscapesJSONStrings(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte("RENDER_NAME: 'My \"best\" server \\ <3'\nRENDER_PASSWORD: 1234\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := `{"name": {{ (datasource "config").RENDER_NAME | print | toJSON }}, "password": {{ (datasource "config").RENDER_PASSWORD | print | toJSON }}, "motd": {{ (datasource "config").RENDER_MOTD | default "" | print | toJSON }}}`
+	data, err := New(cfg).Render("test", tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got map[string]string
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("rendered output is not valid JSON: %v\n%s", err, data)
+	}
+	want := map[string]string{"name": `My "best" server \ <3`, "password": "1234", "motd": ""}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+}
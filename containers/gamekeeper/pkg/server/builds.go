@@ -0,0 +1,383 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/archive"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/output"
+)
+
+// buildRecord describes an installed build
+type buildRecord struct {
+	ID               string `json:"id,omitempty"`
+	Version          string `json:"version"`
+	InstalledAtEpoch int64  `json:"installedAtEpoch"`
+	ReplacedAtEpoch  int64  `json:"replacedAtEpoch,omitempty"`
+	WorldSnapshot    bool   `json:"worldSnapshot"`
+}
+
+// pinRecord marks a build that updates must not replace
+type pinRecord struct {
+	Version       string `json:"version"`
+	PinnedAtEpoch int64  `json:"pinnedAtEpoch"`
+}
+
+// buildHistory keeps the last few replaced builds, each with a snapshot of
+// the world taken just before it was replaced, so an update can be undone
+type buildHistory struct {
+	dir         string
+	currentPath string
+	pinPath     string
+	keep        int
+	snapshot    bool
+}
+
+// buildHistory returns the build history stored in gamekeeper's state directory
+func (b *BaseManager) buildHistory() *buildHistory {
+	stateDir := filepath.Join(b.DataDir, stateDirName)
+	return &buildHistory{
+		dir:         filepath.Join(stateDir, "builds"),
+		currentPath: filepath.Join(stateDir, "build.json"),
+		pinPath:     filepath.Join(stateDir, "pinned.json"),
+		keep:        b.Config.GetInt("BUILD_HISTORY", 2),
+		snapshot:    b.Config.GetBool("BUILD_HISTORY_WORLD_SNAPSHOT", true),
+	}
+}
+
+// Current returns the record of the live build
+func (h *buildHistory) Current() buildRecord {
+	var rec buildRecord
+	if data, err := os.ReadFile(h.currentPath); err == nil {
+		json.Unmarshal(data, &rec)
+	}
+	return rec
+}
+
+// SetCurrent records the version of the live build
+func (h *buildHistory) SetCurrent(version string) error {
+	return writeJSON(h.currentPath, buildRecord{
+		Version:          version,
+		InstalledAtEpoch: time.Now().Unix(),
+	})
+}
+
+// Pinned returns the pinned version, or "" when updates are allowed
+func (h *buildHistory) Pinned() string {
+	var pin pinRecord
+	data, err := os.ReadFile(h.pinPath)
+	if err != nil {
+		return ""
+	}
+	if err := json.Unmarshal(data, &pin); err != nil {
+		return ""
+	}
+	if pin.Version == "" {
+		return "unknown"
+	}
+	return pin.Version
+}
+
+// Pin stops updates from replacing the given version
+func (h *buildHistory) Pin(version string) error {
+	return writeJSON(h.pinPath, pinRecord{Version: version, PinnedAtEpoch: time.Now().Unix()})
+}
+
+// Unpin allows updates again
+func (h *buildHistory) Unpin() error {
+	if err := os.Remove(h.pinPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// List returns the kept builds, newest first
+func (h *buildHistory) List() []buildRecord {
+	entries, err := os.ReadDir(h.dir)
+	if err != nil {
+		return nil
+	}
+
+	var records []buildRecord
+	for _, entry := range entries {
+		var rec buildRecord
+		data, err := os.ReadFile(filepath.Join(h.dir, entry.Name(), "build.json"))
+		if err != nil || json.Unmarshal(data, &rec) != nil {
+			continue
+		}
+		rec.ID = entry.Name()
+		records = append(records, rec)
+	}
+
+	// IDs start with the replacement time in milliseconds
+	sort.Slice(records, func(i, j int) bool {
+		return buildStamp(records[i].ID) > buildStamp(records[j].ID)
+	})
+	return records
+}
+
+// Find returns the newest kept build, or the one matching a version or ID
+func (h *buildHistory) Find(version string) (buildRecord, error) {
+	records := h.List()
+	if len(records) == 0 {
+		return buildRecord{}, fmt.Errorf("no previous builds available")
+	}
+	if version == "" {
+		return records[0], nil
+	}
+	var available []string
+	for _, rec := range records {
+		if rec.Version == version || rec.ID == version {
+			return rec, nil
+		}
+		available = append(available, rec.ID)
+	}
+	return buildRecord{}, fmt.Errorf("no kept build matches %q (available: %s)", version, strings.Join(available, ", "))
+}
+
+// SnapshotWorld archives world paths (relative to root) into a temporary file
+func (h *buildHistory) SnapshotWorld(root string, paths []string) (string, error) {
+	if !h.snapshot || h.keep <= 0 || len(paths) == 0 {
+		return "", nil
+	}
+	if err := ensureDir(h.dir); err != nil {
+		return "", err
+	}
+
+	snapshot := filepath.Join(h.dir, ".world-snapshot.tar.gz")
+	if err := archive.Create(snapshot, root, paths); err != nil {
+		os.Remove(snapshot)
+		return "", fmt.Errorf("failed to snapshot world: %w", err)
+	}
+	return snapshot, nil
+}
+
+// Archive stores a displaced build together with its world snapshot
+func (h *buildHistory) Archive(filesDir, snapshot string, rec buildRecord) error {
+	if h.keep <= 0 || isEmptyDir(filesDir) {
+		if snapshot != "" {
+			os.Remove(snapshot)
+		}
+		return nil
+	}
+
+	now := time.Now()
+	rec.ReplacedAtEpoch = now.Unix()
+	rec.WorldSnapshot = snapshot != ""
+	id := fmt.Sprintf("%d", now.UnixMilli())
+	if rec.Version != "" {
+		id += "-" + safeName(rec.Version)
+	}
+
+	recDir := filepath.Join(h.dir, id)
+	if err := ensureDir(recDir); err != nil {
+		return err
+	}
+	if err := os.Rename(filesDir, filepath.Join(recDir, "files")); err != nil {
+		return fmt.Errorf("failed to archive previous build: %w", err)
+	}
+	if snapshot != "" {
+		if err := os.Rename(snapshot, filepath.Join(recDir, "world.tar.gz")); err != nil {
+			return fmt.Errorf("failed to archive world snapshot: %w", err)
+		}
+	}
+	if err := writeJSON(filepath.Join(recDir, "build.json"), rec); err != nil {
+		return err
+	}
+
+	h.prune()
+	return nil
+}
+
+// Remove deletes a kept build
+func (h *buildHistory) Remove(rec buildRecord) error {
+	return os.RemoveAll(filepath.Join(h.dir, rec.ID))
+}
+
+// prune removes builds beyond the configured history size
+func (h *buildHistory) prune() {
+	records := h.List()
+	for i := h.keep; i < len(records); i++ {
+		os.RemoveAll(filepath.Join(h.dir, records[i].ID))
+	}
+}
+
+// commitStaged swaps a validated staged build into place, keeping the
+// build it replaces (and a snapshot of the world) for rollback
+func (b *BaseManager) commitStaged(stage *stagedInstall, version string, worldPaths []string) error {
+	history := b.buildHistory()
+
+	output.Step("Snapshotting world")
+	snapshot, err := history.SnapshotWorld(b.BaseDir, worldPaths)
+	if err != nil {
+		output.Error(err.Error())
+		return err
+	}
+	output.Success()
+
+	output.Step("Swapping in new build")
+	if err := stage.Swap(); err != nil {
+		output.Error(err.Error())
+		os.Remove(snapshot)
+		return err
+	}
+	output.Success()
+
+	if err := history.Archive(stage.previousDir, snapshot, history.Current()); err != nil {
+		// The new build is live; losing the old one only costs the rollback
+		output.Warning(err.Error())
+	}
+	return history.SetCurrent(version)
+}
+
+// rollback restores a kept build through the same staged swap used by updates,
+// optionally restores its world snapshot, and pins it against auto-update
+func (b *BaseManager) rollback(stage *stagedInstall, version string, restoreWorld bool, worldPaths []string) error {
+	history := b.buildHistory()
+
+	rec, err := history.Find(version)
+	if err != nil {
+		return err
+	}
+	label := rec.Version
+	if label == "" {
+		label = rec.ID
+	}
+
+	if restoreWorld && !rec.WorldSnapshot {
+		return fmt.Errorf("no world snapshot was kept for build %s", label)
+	}
+
+	output.Step(fmt.Sprintf("Restoring build %s", label))
+	if err := stage.Prepare(false); err != nil {
+		output.Error(err.Error())
+		return err
+	}
+	recDir := filepath.Join(history.dir, rec.ID)
+	if err := moveEntries(filepath.Join(recDir, "files"), stage.stagingDir); err != nil {
+		output.Error(err.Error())
+		moveEntries(stage.stagingDir, filepath.Join(recDir, "files"))
+		return fmt.Errorf("failed to stage kept build: %w", err)
+	}
+
+	// Take the world snapshot out of the record, so archiving the current
+	// build can't prune it before it is restored
+	worldSnapshot := filepath.Join(filepath.Dir(history.dir), ".restore-world.tar.gz")
+	if restoreWorld {
+		if err := os.Rename(filepath.Join(recDir, "world.tar.gz"), worldSnapshot); err != nil {
+			output.Error(err.Error())
+			moveEntries(stage.stagingDir, filepath.Join(recDir, "files"))
+			return fmt.Errorf("failed to read world snapshot: %w", err)
+		}
+		defer os.Remove(worldSnapshot)
+	}
+	output.Success()
+
+	// The record is dropped before archiving the current build so it doesn't
+	// count against the history size; it is recreated if the swap fails
+	if err := history.Remove(rec); err != nil {
+		output.Warning(err.Error())
+	}
+
+	// Keep the build being rolled back from, so the rollback can be undone too
+	if err := b.commitStaged(stage, rec.Version, worldPaths); err != nil {
+		moveEntries(stage.stagingDir, filepath.Join(recDir, "files"))
+		if restoreWorld {
+			os.Rename(worldSnapshot, filepath.Join(recDir, "world.tar.gz"))
+		}
+		writeJSON(filepath.Join(recDir, "build.json"), rec)
+		return err
+	}
+
+	if restoreWorld {
+		output.Step("Restoring world snapshot")
+		for _, p := range worldPaths {
+			if err := os.RemoveAll(filepath.Join(b.BaseDir, p)); err != nil {
+				output.Error(err.Error())
+				return err
+			}
+		}
+		if err := archive.Extract(worldSnapshot, b.BaseDir, archive.Options{}); err != nil {
+			output.Error(err.Error())
+			return fmt.Errorf("failed to restore world: %w", err)
+		}
+		output.Success()
+	}
+
+	output.Step("Pinning build")
+	if err := history.Pin(rec.Version); err != nil {
+		output.Error(err.Error())
+		return err
+	}
+	output.SuccessWithMessage(fmt.Sprintf("%s (run 'gamekeeper update unpin' to resume updates)", label))
+	return nil
+}
+
+// pinnedSkip reports (and prints) whether updates are blocked by a pin
+func (b *BaseManager) pinnedSkip() bool {
+	pinned := b.buildHistory().Pinned()
+	if pinned == "" {
+		return false
+	}
+	output.Step("Server files")
+	output.SuccessWithMessage(fmt.Sprintf("pinned to %s after rollback, skipping update", pinned))
+	return true
+}
+
+// Unpin allows updates to replace a rolled-back build again
+func (b *BaseManager) Unpin() error {
+	return b.buildHistory().Unpin()
+}
+
+// buildStamp returns the millisecond timestamp a build ID starts with
+func buildStamp(id string) int64 {
+	stamp, _ := strconv.ParseInt(strings.SplitN(id, "-", 2)[0], 10, 64)
+	return stamp
+}
+
+// moveEntries renames every entry of src into dst
+func moveEntries(src, dst string) error {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	if err := ensureDir(dst); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := os.Rename(filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeJSON writes v as indented JSON
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ensureDir(filepath.Dir(path)); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// isEmptyDir reports whether dir is missing or has no entries
+func isEmptyDir(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	return err != nil || len(entries) == 0
+}
+
+// safeName makes a version string usable as a directory name
+func safeName(s string) string {
+	return regexp.MustCompile(`[^A-Za-z0-9._-]`).ReplaceAllString(s, "_")
+}
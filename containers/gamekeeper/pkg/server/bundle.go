@@ -0,0 +1,187 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/archive"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/fsutil"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/output"
+)
+
+const bundleManifestName = "manifest.json"
+
+// bundleManifest describes an offline bundle
+type bundleManifest struct {
+	SchemaVersion  int    `json:"schemaVersion"`
+	Game           string `json:"game"`
+	Version        string `json:"version"`
+	CreatedAtEpoch int64  `json:"createdAtEpoch"`
+	// Files maps every bundled file (slash-separated, relative) to its sha256
+	Files map[string]string `json:"files"`
+}
+
+// bundleWorkDir returns an empty scratch directory for assembling or unpacking a bundle
+func (b *BaseManager) bundleWorkDir() (string, error) {
+	dir := filepath.Join(b.DataDir, stateDirName, "bundle")
+	if err := os.RemoveAll(dir); err != nil {
+		return "", err
+	}
+	return dir, ensureDir(dir)
+}
+
+// writeBundle hashes everything in workDir, adds the manifest and archives it to dest
+func (b *BaseManager) writeBundle(dest, workDir, version string) error {
+	manifest := bundleManifest{
+		SchemaVersion:  1,
+		Game:           b.GameType,
+		Version:        version,
+		CreatedAtEpoch: time.Now().Unix(),
+		Files:          map[string]string{},
+	}
+
+	output.Step("Hashing bundle contents")
+	err := filepath.Walk(workDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(workDir, path)
+		if err != nil {
+			return err
+		}
+		sum, err := sha256File(path)
+		if err != nil {
+			return err
+		}
+		manifest.Files[filepath.ToSlash(rel)] = sum
+		return nil
+	})
+	if err != nil {
+		output.Error(err.Error())
+		return err
+	}
+	output.SuccessWithMessage(fmt.Sprintf("%d files", len(manifest.Files)))
+
+	if err := writeJSON(filepath.Join(workDir, bundleManifestName), manifest); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(workDir)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+
+	output.Step(fmt.Sprintf("Writing %s", filepath.Base(dest)))
+	if err := ensureDir(filepath.Dir(dest)); err != nil {
+		return err
+	}
+	if err := archive.Create(dest, workDir, names); err != nil {
+		os.Remove(dest)
+		output.Error(err.Error())
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	output.Success()
+	return nil
+}
+
+// openBundle unpacks src into a scratch directory and verifies it against its manifest
+func (b *BaseManager) openBundle(src string) (string, *bundleManifest, error) {
+	workDir, err := b.bundleWorkDir()
+	if err != nil {
+		return "", nil, err
+	}
+
+	output.Step(fmt.Sprintf("Unpacking %s", filepath.Base(src)))
+	if err := archive.Extract(src, workDir, archive.Options{Progress: output.Progress()}); err != nil {
+		output.Error(err.Error())
+		return "", nil, fmt.Errorf("failed to unpack bundle: %w", err)
+	}
+	output.Success()
+
+	data, err := os.ReadFile(filepath.Join(workDir, bundleManifestName))
+	if err != nil {
+		return "", nil, fmt.Errorf("bundle has no manifest: %w", err)
+	}
+	var manifest bundleManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return "", nil, fmt.Errorf("invalid bundle manifest: %w", err)
+	}
+	if manifest.Game != b.GameType {
+		return "", nil, fmt.Errorf("bundle is for %s, not %s", manifest.Game, b.GameType)
+	}
+
+	output.Step("Verifying bundle contents")
+	for rel, want := range manifest.Files {
+		got, err := sha256File(filepath.Join(workDir, filepath.FromSlash(rel)))
+		if err != nil {
+			output.Error(err.Error())
+			return "", nil, fmt.Errorf("bundle is incomplete: %w", err)
+		}
+		if got != want {
+			output.Error(rel)
+			return "", nil, fmt.Errorf("bundle file %s is corrupt", rel)
+		}
+	}
+	output.SuccessWithMessage(fmt.Sprintf("%d files", len(manifest.Files)))
+	return workDir, &manifest, nil
+}
+
+// linkTree mirrors src at dst using hard links where possible, so bundling a
+// multi-gigabyte build doesn't need the same space again
+func linkTree(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		default:
+			if err := ensureDir(filepath.Dir(target)); err != nil {
+				return err
+			}
+			if err := os.Link(path, target); err == nil {
+				return nil
+			}
+			return fsutil.CopyFile(path, target)
+		}
+	})
+}
+
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,465 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/config"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/configfile"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/gamedef"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/output"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/rcon"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/render"
+)
+
+// DefinitionManager runs a game server from its declarative definition, so
+// games that only need installing, configuring and starting need no Go code
+type DefinitionManager struct {
+	*BaseManager
+	def *gamedef.Definition
+}
+
+// NewDefinitionManager creates a manager for a game definition
+func NewDefinitionManager(cfg *config.Config, def *gamedef.Definition) *DefinitionManager {
+	baseDir := cfg.GetString("BASE_DIR", "/home/kubelize/server")
+
+	return &DefinitionManager{
+		BaseManager: &BaseManager{
+			GameType: def.Name,
+			Config:   cfg,
+			BaseDir:  baseDir,
+			DataDir:  baseDir,
+		},
+		def: def,
+	}
+}
+
+// staged returns a copy of the manager rooted at a staged build
+func (d *DefinitionManager) staged(dir string) *DefinitionManager {
+	return &DefinitionManager{BaseManager: d.withRoot(dir), def: d.def}
+}
+
+func (d *DefinitionManager) Setup() error {
+	return d.ensureDirectories(d.BaseDir)
+}
+
+func (d *DefinitionManager) Update(force bool) error {
+	if d.def.Install.Steam != nil {
+		return d.steamUpdate()
+	}
+	return d.downloadUpdate(force)
+}
+
+func (d *DefinitionManager) CheckUpdate() (bool, string, error) {
+	if d.def.Install.Steam != nil {
+		// SteamCMD handles updates automatically
+		return false, "", nil
+	}
+	src, err := d.downloadSource()
+	if err != nil {
+		return false, "", err
+	}
+	return src.version != d.buildHistory().Current().Version, src.version, nil
+}
+
+// Rollback restores a previously installed build
+func (d *DefinitionManager) Rollback(version string, restoreWorld bool) error {
+	stage := d.stagedInstall(d.BaseDir, nil, d.preservePaths())
+	return d.rollback(stage, version, restoreWorld, d.worldPaths())
+}
+
+// worldPaths returns world/save paths relative to BaseDir. Paths that render
+// outside BaseDir are left out: updates never touch them.
+func (d *DefinitionManager) worldPaths() []string {
+	r := d.renderer()
+	var paths []string
+	for _, p := range d.def.WorldPaths {
+		expanded, err := d.expand(r, "world_paths", p)
+		if err != nil {
+			// Keep the literal path rather than lose track of world data
+			output.Warning(err.Error())
+			expanded = p
+		}
+		rel, err := filepath.Rel(d.BaseDir, d.serverPath(expanded))
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		paths = append(paths, rel)
+	}
+	return paths
+}
+
+// preservePaths returns paths (relative to BaseDir) an update must not replace
+func (d *DefinitionManager) preservePaths() []string {
+	paths := append([]string{}, d.worldPaths()...)
+	paths = append(paths, d.def.PreservePaths...)
+	return append(paths, strings.Fields(d.Config.GetString("PRESERVE_PATHS", ""))...)
+}
+
+// Configure renders the definition's config templates. CONFIG_MODE overrides
+// the mode of every template.
+func (d *DefinitionManager) Configure() error {
+	return d.configure(nil)
+}
+
+// configure renders the config templates; adjust, if set, edits each rendered
+// file before it is written
+func (d *DefinitionManager) configure(adjust func(target string, data []byte) ([]byte, error)) error {
+	if len(d.def.Templates) == 0 {
+		output.Info("No config templates")
+		return nil
+	}
+
+	r := d.renderer()
+	for i, t := range d.def.Templates {
+		source, err := d.expand(r, fmt.Sprintf("templates[%d].source", i), t.Source)
+		if err != nil {
+			return err
+		}
+		target, err := d.expand(r, fmt.Sprintf("templates[%d].target", i), t.Target)
+		if err != nil {
+			return err
+		}
+
+		output.Step(fmt.Sprintf("Rendering %s", target))
+		mode, err := configfile.ParseMode(d.Config.GetString("CONFIG_MODE", string(t.ConfigMode())))
+		if err != nil {
+			output.Error(err.Error())
+			return err
+		}
+
+		data, err := r.RenderFile(source)
+		if err != nil {
+			output.Error(err.Error())
+			return err
+		}
+		if adjust != nil {
+			if data, err = adjust(target, data); err != nil {
+				output.Error(err.Error())
+				return err
+			}
+		}
+
+		path := d.serverPath(target)
+		if err := ensureDir(filepath.Dir(path)); err != nil {
+			output.Error(err.Error())
+			return err
+		}
+		file := configfile.File{
+			Path:     path,
+			Mode:     mode,
+			StateDir: filepath.Join(d.DataDir, stateDirName),
+		}
+		result, err := file.Write(data)
+		if err != nil {
+			output.Error(err.Error())
+			return err
+		}
+		output.SuccessWithMessage(result)
+	}
+	return nil
+}
+
+// Validate checks the installed build and that the command line renders
+func (d *DefinitionManager) Validate() error {
+	buildErr := d.validateBuild()
+	if _, _, err := d.renderStart(); err != nil {
+		return errors.Join(buildErr, err)
+	}
+	return buildErr
+}
+
+// validateBuild checks that a build has the files needed to start
+func (d *DefinitionManager) validateBuild() error {
+	if !fileExists(d.BaseDir) {
+		return fmt.Errorf("server directory does not exist: %s", d.BaseDir)
+	}
+
+	install := d.def.Install
+	switch {
+	case install.Steam != nil:
+		if manifest := steamManifestPath(d.BaseDir, install.Steam.AppID); !fileExists(manifest) {
+			return fmt.Errorf("required file missing: %s", manifest)
+		}
+	case install.Java != nil:
+		if jar := d.serverPath(d.jarName()); !fileExists(jar) {
+			return fmt.Errorf("required file missing: %s", jar)
+		}
+	default:
+		if isEmptyDir(d.BaseDir) {
+			return fmt.Errorf("server directory is empty: %s", d.BaseDir)
+		}
+	}
+
+	// Commands in the server directory must have been installed
+	if command := d.def.Start.Command; strings.HasPrefix(command, "./") && !strings.Contains(command, "{{") {
+		if path := d.serverPath(command); !fileExists(path) {
+			return fmt.Errorf("start command missing: %s", path)
+		}
+	}
+	return nil
+}
+
+func (d *DefinitionManager) Start() error {
+	command, args, err := d.commandLine()
+	if err != nil {
+		return err
+	}
+	return d.launch(command, args, filepath.Join(d.BaseDir, "console.log"))
+}
+
+// launch runs the server in its tmux session until it exits, watching
+// logFile for the ready line
+func (d *DefinitionManager) launch(command string, args []string, logFile string) error {
+	consolePort := d.Config.GetString("CONSOLE_PORT", "8080")
+	sessionName := d.sessionName()
+
+	for _, p := range d.Ports() {
+		output.Info(fmt.Sprintf("Port %d/%s (%s)", p.Port, p.ProtocolName(), p.Name))
+	}
+
+	if d.def.Ready.LogPattern != "" {
+		var offset int64
+		if info, err := os.Stat(logFile); err == nil {
+			offset = info.Size()
+		}
+		os.Remove(d.readyPath())
+		go d.watchReady(logFile, offset, regexp.MustCompile(d.def.Ready.LogPattern))
+	}
+
+	// Save and stop through the console when the container is asked to stop
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM, os.Interrupt)
+	defer func() {
+		signal.Stop(sigs)
+		close(sigs)
+	}()
+	go func() {
+		if _, ok := <-sigs; ok {
+			if err := d.Stop(); err != nil {
+				output.Warning(err.Error())
+			}
+		}
+	}()
+
+	return rcon.StartServerWithTmux(consolePort, sessionName, command, args, d.BaseDir)
+}
+
+// Stop runs the save and stop console commands, or interrupts the server
+// when the definition has no stop command, waits up to STOP_TIMEOUT_SECONDS
+// for it to exit and then ends its session
+func (d *DefinitionManager) Stop() error {
+	sessionName := d.sessionName()
+	if !rcon.SessionRunning(sessionName) {
+		return nil
+	}
+
+	console := d.def.Console
+	if console.Save != "" {
+		output.Step("Saving world")
+		if err := rcon.SendCommand(sessionName, console.Save); err != nil {
+			output.Error(err.Error())
+		} else {
+			time.Sleep(time.Duration(d.Config.GetInt("SAVE_WAIT_SECONDS", 5)) * time.Second)
+			output.Success()
+		}
+	}
+
+	output.Step("Stopping server")
+	var err error
+	if console.Stop != "" {
+		err = rcon.SendCommand(sessionName, console.Stop)
+	} else {
+		// Servers without a console command shut down cleanly on SIGINT
+		err = rcon.SendInterrupt(sessionName)
+	}
+	if err != nil {
+		output.Error(err.Error())
+	} else {
+		deadline := time.Now().Add(time.Duration(d.Config.GetInt("STOP_TIMEOUT_SECONDS", 60)) * time.Second)
+		for rcon.SessionRunning(sessionName) && time.Now().Before(deadline) {
+			time.Sleep(time.Second)
+		}
+		if !rcon.SessionRunning(sessionName) {
+			output.Success()
+			return nil
+		}
+		output.Error("server did not stop in time")
+	}
+
+	return rcon.KillSession(sessionName)
+}
+
+// Ports returns the definition's ports with config overrides applied
+func (d *DefinitionManager) Ports() []gamedef.Port {
+	ports := make([]gamedef.Port, len(d.def.Ports))
+	for i, p := range d.def.Ports {
+		if p.ConfigKey != "" {
+			p.Port = d.Config.GetInt(p.ConfigKey, p.Port)
+		}
+		ports[i] = p
+	}
+	return ports
+}
+
+// commandLine builds the full start command. Java installs run the jar
+// with the JVM heap and JAVA_ARGS ahead of the definition's args.
+func (d *DefinitionManager) commandLine() (string, []string, error) {
+	command, args, err := d.renderStart()
+	if err != nil {
+		return "", nil, err
+	}
+	if command == "" {
+		// Heap flags go first so anything in JAVA_ARGS takes precedence
+		java := d.jvmHeap(nil)
+		java = append(java, splitArgs(d.Config.GetString("JAVA_ARGS", ""))...)
+		java = append(java, "-jar", d.jarName())
+		command, args = "java", append(java, args...)
+	}
+
+	if len(d.def.Start.Env) > 0 {
+		env, err := d.environment(d.renderer())
+		if err != nil {
+			return "", nil, err
+		}
+		command, args = "env", append(append(env, command), args...)
+	}
+	return command, args, nil
+}
+
+// renderStart renders the start command and its args. START_COMMAND
+// replaces the command and START_ARGS are added after the definition's args.
+// The command is empty for java installs that leave it to gamekeeper.
+func (d *DefinitionManager) renderStart() (string, []string, error) {
+	r := d.renderer()
+	start := d.def.Start
+
+	var args []string
+	for i, arg := range start.Args {
+		value, err := d.expand(r, fmt.Sprintf("start.args[%d]", i), arg)
+		if err != nil {
+			return "", nil, err
+		}
+		if value != "" {
+			args = append(args, value)
+		}
+	}
+	args = append(args, splitArgs(d.Config.GetString("START_ARGS", ""))...)
+
+	command, err := d.expand(r, "start.command", start.Command)
+	if err != nil {
+		return "", nil, err
+	}
+	return d.Config.GetString("START_COMMAND", command), args, nil
+}
+
+// environment renders the start environment as sorted KEY=value pairs
+func (d *DefinitionManager) environment(r *render.Renderer) ([]string, error) {
+	keys := make([]string, 0, len(d.def.Start.Env))
+	for k := range d.def.Start.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	env := make([]string, 0, len(keys))
+	for _, k := range keys {
+		value, err := d.expand(r, "start.env."+k, d.def.Start.Env[k])
+		if err != nil {
+			return nil, err
+		}
+		env = append(env, k+"="+value)
+	}
+	return env, nil
+}
+
+// renderer returns a renderer for the definition's templates, with the
+// resolved ports as the "ports" datasource
+func (d *DefinitionManager) renderer() *render.Renderer {
+	r := render.New(d.Config)
+	ports := make(map[string]interface{})
+	for _, p := range d.Ports() {
+		ports[p.Name] = p.Port
+	}
+	r.Set("ports", ports)
+	return r
+}
+
+// expand renders one templated field of the definition
+func (d *DefinitionManager) expand(r *render.Renderer, field, text string) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	out, err := r.Render(d.def.Name+" "+field, text)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", field, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// serverPath resolves a path from the definition against the server directory
+func (d *DefinitionManager) serverPath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(d.BaseDir, path)
+}
+
+func (d *DefinitionManager) sessionName() string {
+	return d.Config.GetString("TMUX_SESSION_NAME", fmt.Sprintf("%s-server", d.GameType))
+}
+
+// readyPath is created once the server has logged its ready line, for
+// readiness probes
+func (d *DefinitionManager) readyPath() string {
+	return filepath.Join(d.DataDir, stateDirName, "ready")
+}
+
+// watchReady follows the console log from offset until a line matches the
+// ready pattern, then marks the server ready
+func (d *DefinitionManager) watchReady(logFile string, offset int64, pattern *regexp.Regexp) {
+	var file *os.File
+	for file == nil {
+		f, err := os.Open(logFile)
+		if err != nil {
+			time.Sleep(time.Second)
+			continue
+		}
+		file = f
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return
+	}
+
+	reader := bufio.NewReader(file)
+	var line string
+	for {
+		chunk, err := reader.ReadString('\n')
+		line += chunk
+		if err != nil {
+			// Partial line; wait for the rest
+			time.Sleep(time.Second)
+			continue
+		}
+		if pattern.MatchString(line) {
+			break
+		}
+		line = ""
+	}
+
+	if err := writeJSON(d.readyPath(), map[string]int64{"readyAtEpoch": time.Now().Unix()}); err != nil {
+		output.Warning(fmt.Sprintf("failed to mark server ready: %v", err))
+	}
+	output.Event("server_ready", fmt.Sprintf("%s is ready", d.def.Title()), map[string]string{"game": d.def.Name})
+}
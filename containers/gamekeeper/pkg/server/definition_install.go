@@ -0,0 +1,160 @@
+package server
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/archive"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/output"
+)
+
+// downloadSource is a rendered url or java install source
+type downloadSource struct {
+	url      string
+	checksum string
+	version  string
+	// versioned means the definition set the version rather than it being
+	// taken from the URL
+	versioned bool
+	// file is the name the download is saved under
+	file string
+}
+
+// downloadSource renders the definition's url or java install source
+func (d *DefinitionManager) downloadSource() (downloadSource, error) {
+	r := d.renderer()
+	var src downloadSource
+	var rawURL, checksum, version string
+	if j := d.def.Install.Java; j != nil {
+		rawURL, checksum, version = j.URL, j.Checksum, j.Version
+		src.file = d.jarName()
+	} else {
+		u := d.def.Install.URL
+		rawURL, checksum, version = u.URL, u.Checksum, u.Version
+	}
+
+	var err error
+	if src.url, err = d.expand(r, "install.url", rawURL); err != nil {
+		return src, err
+	}
+	if src.checksum, err = d.expand(r, "install.checksum", checksum); err != nil {
+		return src, err
+	}
+	if src.version, err = d.expand(r, "install.version", version); err != nil {
+		return src, err
+	}
+
+	name := src.url
+	if parsed, err := url.Parse(src.url); err == nil {
+		name = parsed.Path
+	}
+	name = path.Base(name)
+	if src.file == "" {
+		src.file = name
+	}
+	src.versioned = src.version != ""
+	if !src.versioned {
+		src.version = name
+	}
+	return src, nil
+}
+
+// jarName is the server jar of a java install, relative to BaseDir
+func (d *DefinitionManager) jarName() string {
+	if j := d.def.Install.Java; j != nil && j.Jar != "" {
+		return j.Jar
+	}
+	return "server.jar"
+}
+
+// downloadUpdate installs a url or java source when its version changed
+func (d *DefinitionManager) downloadUpdate(force bool) error {
+	src, err := d.downloadSource()
+	if err != nil {
+		return err
+	}
+
+	installed := d.validateBuild() == nil
+	if installed && d.pinnedSkip() {
+		return nil
+	}
+	if installed && !force && d.buildHistory().Current().Version == src.version {
+		output.Step("Server files")
+		output.SuccessWithMessage(fmt.Sprintf("up to date (%s)", src.version))
+		return nil
+	}
+
+	if !d.stagedUpdatesEnabled() {
+		if err := d.installDownload(src, d.BaseDir); err != nil {
+			return err
+		}
+		return d.buildHistory().SetCurrent(src.version)
+	}
+
+	stage := d.stagedInstall(d.BaseDir, nil, d.preservePaths())
+	if err := stage.Prepare(false); err != nil {
+		return err
+	}
+	if err := d.installDownload(src, stage.stagingDir); err != nil {
+		stage.Discard()
+		return err
+	}
+	if err := d.commitStaged(stage, src.version, d.worldPaths()); err != nil {
+		stage.Discard()
+		return err
+	}
+	return nil
+}
+
+// installDownload downloads the source into dir, extracting archives
+func (d *DefinitionManager) installDownload(src downloadSource, dir string) error {
+	// Without a checksum the cache is keyed on the version, which is only
+	// trusted when the definition sets it; a URL's file name (like
+	// server.zip) can stay the same across releases
+	cacheKey := ""
+	if src.checksum == "" && src.versioned {
+		cacheKey = fmt.Sprintf("%s/%s/%s", d.GameType, safeName(src.version), src.file)
+	}
+
+	output.Step(fmt.Sprintf("Downloading %s %s", d.def.Title(), src.version))
+	if d.def.Install.Java != nil || !archive.IsArchive(src.file) {
+		dest := filepath.Join(dir, src.file)
+		if err := d.download(src.url, dest, src.checksum, cacheKey); err != nil {
+			output.Error(err.Error())
+			return err
+		}
+		if d.def.Install.URL != nil {
+			// A single file is the server binary itself
+			if err := os.Chmod(dest, 0755); err != nil {
+				output.Error(err.Error())
+				return err
+			}
+		}
+		output.Success()
+		return nil
+	}
+
+	downloads := filepath.Join(d.DataDir, stateDirName, "downloads")
+	if err := ensureDir(downloads); err != nil {
+		output.Error(err.Error())
+		return err
+	}
+	file := filepath.Join(downloads, src.file)
+	defer os.Remove(file)
+	if err := d.download(src.url, file, src.checksum, cacheKey); err != nil {
+		output.Error(err.Error())
+		return err
+	}
+	output.Success()
+
+	output.Step(fmt.Sprintf("Extracting %s", src.file))
+	if err := archive.Extract(file, dir, archive.Options{Progress: output.Progress()}); err != nil {
+		output.Error(err.Error())
+		return fmt.Errorf("failed to extract %s: %w", src.file, err)
+	}
+	output.Success()
+	return nil
+}
@@ -1,17 +1,24 @@
 package server
 
 import (
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"strings"
 
+	"github.com/kubelize/game-servers/gamekeeper/pkg/archive"
 	"github.com/kubelize/game-servers/gamekeeper/pkg/config"
 	"github.com/kubelize/game-servers/gamekeeper/pkg/curseforge"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/download"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/gamedef"
 	"github.com/kubelize/game-servers/gamekeeper/pkg/output"
 	"github.com/kubelize/game-servers/gamekeeper/pkg/rcon"
 )
 
+// hytaleBuildEntries are the DataDir entries that make up a Hytale server build
+var hytaleBuildEntries = []string{"Server", "Assets.zip"}
+
 // HytaleManager manages Hytale game servers
 type HytaleManager struct {
 	*BaseManager
@@ -20,11 +27,25 @@ type HytaleManager struct {
 	assetsZipPath  string
 }
 
+func init() {
+	Register(Game{
+		Name:         "hytale",
+		DisplayName:  "Hytale",
+		Aliases:      []string{"ht"},
+		Capabilities: []gamedef.Capability{gamedef.CapabilityMods, gamedef.CapabilityBackups},
+		Defaults:     map[string]string{"SERVER_PORT": "5520"},
+	}, func(cfg *config.Config) Manager { return NewHytaleManager(cfg) })
+}
+
 // NewHytaleManager creates a new Hytale server manager
 func NewHytaleManager(cfg *config.Config) *HytaleManager {
 	baseDir := cfg.GetString("BASE_DIR", "/home/kubelize/server")
-	dataDir := filepath.Join(baseDir, "data")
+	return newHytaleManager(cfg, baseDir, filepath.Join(baseDir, "data"))
+}
 
+// newHytaleManager creates a Hytale manager with explicit directories,
+// e.g. to validate a staged build
+func newHytaleManager(cfg *config.Config, baseDir, dataDir string) *HytaleManager {
 	return &HytaleManager{
 		BaseManager: &BaseManager{
 			GameType: "hytale",
@@ -49,59 +70,214 @@ func (h *HytaleManager) Setup() error {
 func (h *HytaleManager) Update(force bool) error {
 	// Check if files exist and auto-update is enabled
 	filesExist := fileExists(h.serverJarPath) && fileExists(h.assetsZipPath)
-	autoUpdate := h.Config.GetBool("HYTALE_AUTO_UPDATE", true)
+	autoUpdate := AutoUpdateEnabled(h.Config, h.GameType)
 
-	shouldDownload := !filesExist || (autoUpdate && force)
-
-	if !shouldDownload && filesExist {
+	if filesExist && !autoUpdate && !force {
 		output.Step("Server files")
 		output.SuccessWithMessage("already downloaded (auto-update disabled)")
 		return nil
 	}
 
-	// Ensure downloader exists
-	if !fileExists(h.downloaderPath) {
-		output.Step("Downloading hytale-downloader")
-		url := h.Config.GetString("HYTALE_DOWNLOADER_URL", 
-			"https://drive.kubelize.com/public.php/dav/files/HJqqWZx5522wnoT")
-		
-		if err := downloadFile(url, h.downloaderPath); err != nil {
-			output.Error(err.Error())
-			return fmt.Errorf("failed to download hytale-downloader: %w", err)
+	if filesExist && h.pinnedSkip() {
+		return nil
+	}
+
+	if err := h.ensureDownloader(); err != nil {
+		return err
+	}
+
+	// Only download when a newer version exists, unless forced
+	output.Step("Checking latest version")
+	latest, err := h.latestVersion()
+	if err != nil {
+		output.Warning(fmt.Sprintf("could not determine latest version: %v", err))
+	} else {
+		output.SuccessWithMessage(latest)
+	}
+
+	if filesExist && !force {
+		installed := h.installedVersion()
+		if latest == "" {
+			output.Step("Server files")
+			output.SuccessWithMessage("keeping installed version")
+			return nil
+		}
+		if installed != "" && archive.CompareVersions(latest, installed) <= 0 {
+			output.Step("Server files")
+			output.SuccessWithMessage(fmt.Sprintf("up to date (%s)", installed))
+			return nil
 		}
-		
-		if err := os.Chmod(h.downloaderPath, 0755); err != nil {
+	}
+
+	if !h.stagedUpdatesEnabled() {
+		if err := h.fetchServerZip(filepath.Join(h.DataDir, "hytale-server.zip"), latest); err != nil {
+			return err
+		}
+		output.Step("Extracting server files")
+		if err := h.extractLatestZip(h.DataDir); err != nil {
 			output.Error(err.Error())
-			return fmt.Errorf("failed to make downloader executable: %w", err)
+			return err
 		}
-		output.Success()
+		return h.buildHistory().SetCurrent(latest)
+	}
+
+	// Download and extract next to the live build, then swap it in
+	stage := h.stagedInstall(h.DataDir, hytaleBuildEntries, nil)
+	if err := stage.Prepare(false); err != nil {
+		return err
+	}
+
+	if err := h.fetchServerZip(filepath.Join(stage.stagingDir, "hytale-server.zip"), latest); err != nil {
+		stage.Discard()
+		return err
+	}
+
+	output.Step("Extracting server files")
+	if err := h.extractLatestZip(stage.stagingDir); err != nil {
+		output.Error(err.Error())
+		stage.Discard()
+		return err
+	}
+
+	output.Step("Validating staged build")
+	if err := newHytaleManager(h.Config, h.BaseDir, stage.stagingDir).validateBuild(); err != nil {
+		output.Error(err.Error())
+		stage.Discard()
+		return fmt.Errorf("staged build is invalid: %w", err)
+	}
+	output.Success()
+
+	if err := h.commitStaged(stage, latest, h.worldPaths()); err != nil {
+		stage.Discard()
+		return err
+	}
+	return nil
+}
+
+// Rollback restores a previously installed build
+func (h *HytaleManager) Rollback(version string, restoreWorld bool) error {
+	stage := h.stagedInstall(h.DataDir, hytaleBuildEntries, nil)
+	return h.rollback(stage, version, restoreWorld, h.worldPaths())
+}
+
+// worldPaths returns world data paths relative to BaseDir
+func (h *HytaleManager) worldPaths() []string {
+	universe := h.Config.GetString("HYTALE_UNIVERSE", "")
+	if universe == "" || filepath.IsAbs(universe) {
+		universe = "universe"
+	}
+	return []string{filepath.Clean(universe)}
+}
+
+// fetchServerZip places the server zip for version at dest, from the artifact
+// cache when another pod already downloaded it
+func (h *HytaleManager) fetchServerZip(dest, version string) error {
+	client := download.New(h.Config)
+	opts := download.Options{}
+	if version != "" {
+		opts.CacheKey = "hytale-server/" + version
+	}
+
+	if client.FromCache(dest, opts) {
+		output.Step("Server files")
+		output.SuccessWithMessage(fmt.Sprintf("%s from artifact cache", version))
+		return nil
 	}
 
-	// Run the downloader
+	if err := h.runDownloader(dest); err != nil {
+		return err
+	}
+	client.Remember(dest, opts)
+	return nil
+}
+
+// runDownloader runs hytale-downloader non-interactively with the persisted credentials.
+// downloadPath overrides where the game zip is written.
+func (h *HytaleManager) runDownloader(downloadPath string) error {
 	output.Step("Running hytale-downloader")
 	fmt.Println()
-	output.Warning("Authentication may be required - follow prompts")
-	fmt.Println()
-	
-	cmd := exec.Command(h.downloaderPath)
-	cmd.Dir = h.DataDir
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
-	
-	if err := cmd.Run(); err != nil {
+
+	var args []string
+	if downloadPath != "" {
+		args = append(args, "-download-path", downloadPath)
+	}
+
+	if _, err := h.runDownloaderCmd(true, args...); err != nil {
 		output.Error(err.Error())
 		return fmt.Errorf("hytale-downloader failed: %w", err)
 	}
-
-	// Extract downloaded ZIP
-	output.Step("Extracting server files")
-	return h.extractLatestZip()
+	return nil
 }
 
 func (h *HytaleManager) CheckUpdate() (bool, string, error) {
-	// TODO: Implement version checking logic
-	return false, "", nil
+	if err := h.ensureDownloader(); err != nil {
+		return false, "", err
+	}
+
+	latest, err := h.latestVersion()
+	if err != nil {
+		return false, "", err
+	}
+
+	installed := h.installedVersion()
+	if installed == "" {
+		return true, fmt.Sprintf("%s (installed version unknown)", latest), nil
+	}
+	if archive.CompareVersions(latest, installed) > 0 {
+		return true, fmt.Sprintf("%s (installed: %s)", latest, installed), nil
+	}
+	return false, installed, nil
+}
+
+// ensureDownloader downloads hytale-downloader if it is missing
+func (h *HytaleManager) ensureDownloader() error {
+	if fileExists(h.downloaderPath) {
+		return nil
+	}
+
+	output.Step("Downloading hytale-downloader")
+	url := h.Config.GetString("HYTALE_DOWNLOADER_URL",
+		"https://drive.kubelize.com/public.php/dav/files/HJqqWZx5522wnoT")
+
+	// The default URL always serves the latest build, so the downloader is
+	// only cached when a checksum pins it
+	checksum := h.Config.GetString("HYTALE_DOWNLOADER_CHECKSUM", "")
+	cacheKey := ""
+	if checksum != "" {
+		cacheKey = "hytale-downloader/" + url
+	}
+	if err := h.download(url, h.downloaderPath, checksum, cacheKey); err != nil {
+		output.Error(err.Error())
+		return fmt.Errorf("failed to download hytale-downloader: %w", err)
+	}
+
+	if err := os.Chmod(h.downloaderPath, 0755); err != nil {
+		output.Error(err.Error())
+		return fmt.Errorf("failed to make downloader executable: %w", err)
+	}
+	output.Success()
+	return nil
+}
+
+// latestVersion asks hytale-downloader for the latest available server version
+func (h *HytaleManager) latestVersion() (string, error) {
+	out, err := h.runDownloaderCmd(false, "-print-version")
+	if err != nil {
+		return "", fmt.Errorf("hytale-downloader -print-version failed: %w", err)
+	}
+
+	// The version is the last line printed; anything before it is progress output
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	version := strings.TrimSpace(lines[len(lines)-1])
+	if version == "" {
+		return "", fmt.Errorf("hytale-downloader printed no version")
+	}
+	return version, nil
+}
+
+// installedVersion returns the server version recorded after the last extraction
+func (h *HytaleManager) installedVersion() string {
+	return h.buildHistory().Current().Version
 }
 
 func (h *HytaleManager) InstallMods() error {
@@ -147,30 +323,20 @@ func (h *HytaleManager) InstallMods() error {
 }
 
 func (h *HytaleManager) installMod(mod config.ModConfig) error {
-	// TODO: Implement full mod installation
-	// 1. Download from URL
-	// 2. Verify checksum
-	// 3. Extract to installPath
-	// 4. Place config files
-	return nil
+	return h.installConfiguredMod(mod, h.DataDir)
 }
 
-func (h *HytaleManager) Configure() error {
-	configPath := filepath.Join(h.BaseDir, "config.json")
-	
-	// Skip if already exists (unless force)
-	if fileExists(configPath) {
-		output.Step("config.json")
-		output.SuccessWithMessage("already exists")
-		return nil
+// Validate checks the installed build and the server options in config
+func (h *HytaleManager) Validate() error {
+	buildErr := h.validateBuild()
+	if _, err := h.serverOptionArgs(); err != nil {
+		return errors.Join(buildErr, fmt.Errorf("invalid server options:\n%w", err))
 	}
-
-	output.Step("Rendering config.json")
-	// TODO: Implement gomplate rendering or native Go templating
-	return nil
+	return buildErr
 }
 
-func (h *HytaleManager) Validate() error {
+// validateBuild checks that a build has the files needed to start
+func (h *HytaleManager) validateBuild() error {
 	// Check required files exist
 	required := []string{h.serverJarPath, h.assetsZipPath}
 	for _, path := range required {
@@ -189,9 +355,14 @@ func (h *HytaleManager) Start() error {
 	tz := h.Config.GetString("TZ", "UTC")
 
 	// Build Hytale-specific options
-	opts := h.buildServerOptions()
+	opts, err := h.serverOptionArgs()
+	if err != nil {
+		return fmt.Errorf("invalid server options:\n%w", err)
+	}
 
-	args := []string{}
+	// Heap and GC flags go first so anything in JAVA_ARGS takes precedence
+	args := h.jvmHeap(nil)
+	args = append(args, h.jvmGCFlags(hytaleGCFlags)...)
 	if javaArgs != "" {
 		// Parse Java args
 		args = append(args, splitArgs(javaArgs)...)
@@ -200,9 +371,10 @@ func (h *HytaleManager) Start() error {
 		fmt.Sprintf("-Duser.timezone=%s", tz),
 		"-Dterminal.jline=false",
 		"-Dterminal.ansi=true",
-		"-jar", h.serverJarPath,
 	)
-	args = append(args, splitArgs(opts)...)
+	args = append(args, h.aotCacheArgs(args)...)
+	args = append(args, "-jar", h.serverJarPath)
+	args = append(args, opts...)
 	args = append(args,
 		"--assets", h.assetsZipPath,
 		"--bind", fmt.Sprintf("%s:%s", serverIP, serverPort),
@@ -220,79 +392,17 @@ func (h *HytaleManager) Stop() error {
 	return nil
 }
 
-func (h *HytaleManager) buildServerOptions() string {
-	opts := ""
-	
-	// Map of config keys to command-line flags
-	boolFlags := map[string]string{
-		"HYTALE_ACCEPT_EARLY_PLUGINS":     "--accept-early-plugins",
-		"HYTALE_ALLOW_OP":                 "--allow-op",
-		"HYTALE_BACKUP":                   "--backup",
-		"HYTALE_BARE":                     "--bare",
-		"HYTALE_DISABLE_ASSET_COMPARE":    "--disable-asset-compare",
-		"HYTALE_DISABLE_CPB_BUILD":        "--disable-cpb-build",
-		"HYTALE_DISABLE_FILE_WATCHER":     "--disable-file-watcher",
-		"HYTALE_DISABLE_SENTRY":           "--disable-sentry",
-		"HYTALE_EVENT_DEBUG":              "--event-debug",
-		"HYTALE_GENERATE_SCHEMA":          "--generate-schema",
-		"HYTALE_SHUTDOWN_AFTER_VALIDATE":  "--shutdown-after-validate",
-		"HYTALE_SINGLEPLAYER":             "--singleplayer",
-		"HYTALE_VALIDATE_ASSETS":          "--validate-assets",
-		"HYTALE_VALIDATE_WORLD_GEN":       "--validate-world-gen",
-	}
-
-	valueFlags := map[string]string{
-		"HYTALE_AUTH_MODE":          "--auth-mode",
-		"HYTALE_BACKUP_DIR":         "--backup-dir",
-		"HYTALE_BACKUP_FREQUENCY":   "--backup-frequency",
-		"HYTALE_BACKUP_MAX_COUNT":   "--backup-max-count",
-		"HYTALE_BOOT_COMMAND":       "--boot-command",
-		"HYTALE_IDENTITY_TOKEN":     "--identity-token",
-		"HYTALE_SESSION_TOKEN":      "--session-token",
-		"HYTALE_OWNER_NAME":         "--owner-name",
-		"HYTALE_OWNER_UUID":         "--owner-uuid",
-		"HYTALE_TRANSPORT":          "--transport",
-		"HYTALE_UNIVERSE":           "--universe",
-		"HYTALE_WORLD_GEN":          "--world-gen",
-	}
-
-	// Add boolean flags
-	for key, flag := range boolFlags {
-		if h.Config.GetBool(key, false) {
-			opts += " " + flag
-		}
-	}
-
-	// Add value flags
-	for key, flag := range valueFlags {
-		if val := h.Config.GetString(key, ""); val != "" {
-			opts += fmt.Sprintf(" %s=%s", flag, val)
-		}
-	}
-
-	return opts
-}
-
-func (h *HytaleManager) extractLatestZip() error {
-	// Find the latest ZIP file
-	pattern := filepath.Join(h.DataDir, "*.zip")
-	matches, err := filepath.Glob(pattern)
+func (h *HytaleManager) extractLatestZip(dir string) error {
+	// Assets.zip ships inside the server zip, it is not a download
+	zipFile, err := archive.Latest(dir, "*.zip", "Assets.zip")
 	if err != nil {
 		return err
 	}
-	
-	if len(matches) == 0 {
-		return fmt.Errorf("no ZIP file found to extract")
-	}
 
-	// Use the first (newest) match
-	zipFile := matches[0]
-	
-	cmd := exec.Command("unzip", "-q", "-o", zipFile, "-d", h.DataDir)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to extract %s: %w", zipFile, err)
+	if err := archive.Extract(zipFile, dir, archive.Options{Progress: output.Progress()}); err != nil {
+		return fmt.Errorf("failed to extract %s: %w", filepath.Base(zipFile), err)
 	}
 
-	fmt.Printf("  ✓ Extracted: %s\n", filepath.Base(zipFile))
+	output.SuccessWithMessage(filepath.Base(zipFile))
 	return nil
 }
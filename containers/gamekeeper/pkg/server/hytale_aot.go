@@ -0,0 +1,198 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/output"
+)
+
+// aotStamp records what an AOT cache was generated or verified for
+type aotStamp struct {
+	ServerVersion  string `json:"serverVersion"`
+	JavaVersion    string `json:"javaVersion"`
+	JVMArgs        string `json:"jvmArgs"`
+	JarSize        int64  `json:"jarSize"`
+	JarModTime     int64  `json:"jarModTime"`
+	CheckedAtEpoch int64  `json:"checkedAtEpoch"`
+	// Failed is the error of a training run that didn't produce a cache
+	Failed string `json:"failed,omitempty"`
+}
+
+// matches reports whether two stamps describe the same server, JVM and flags
+func (s aotStamp) matches(o aotStamp) bool {
+	s.CheckedAtEpoch, o.CheckedAtEpoch = 0, 0
+	s.Failed, o.Failed = "", ""
+	return s == o
+}
+
+// aotLogLines is how much of a failed training run's output is shown
+const aotLogLines = 20
+
+// aotCacheArgs returns the JVM flags to start with the AOT cache when
+// HYTALE_CACHE is enabled. A missing cache is generated with a training run;
+// one that no longer matches the server jar, Java or JVM flags (typically
+// after an update) is checked by the JVM and regenerated if it's rejected.
+// Cache problems never block startup, the server just starts without it. A
+// failed training run is recorded and not retried until the jar, Java or
+// flags change, so a broken setup doesn't delay every start.
+func (h *HytaleManager) aotCacheArgs(jvmArgs []string) []string {
+	if !h.Config.GetBool("HYTALE_CACHE", false) {
+		return nil
+	}
+
+	cache := h.aotCachePath()
+	stampPath := filepath.Join(h.DataDir, stateDirName, "aot.json")
+	current := h.aotStamp(jvmArgs)
+	flags := []string{"-XX:AOTCache=" + cache}
+
+	var stored aotStamp
+	if data, err := os.ReadFile(stampPath); err == nil {
+		json.Unmarshal(data, &stored)
+	}
+
+	output.Step("AOT cache")
+	if stored.Failed != "" && stored.matches(current) {
+		output.Warning(fmt.Sprintf("training failed for this server, Java and JVM flags (%s); starting without AOT cache", stored.Failed))
+		return nil
+	}
+	if fileExists(cache) {
+		if stored.Failed == "" && stored.matches(current) {
+			output.SuccessWithMessage(filepath.Base(cache))
+			return flags
+		}
+
+		// Changed since the last check; let the JVM decide if it still fits
+		if h.aotCacheUsable(cache, jvmArgs) {
+			h.writeAOTStamp(stampPath, current)
+			output.SuccessWithMessage(fmt.Sprintf("%s verified for %s", filepath.Base(cache), versionLabel(current.ServerVersion)))
+			return flags
+		}
+		output.Warning("cache doesn't match the installed server, regenerating")
+		output.Step("AOT cache")
+	}
+
+	if log, err := h.generateAOTCache(cache, jvmArgs); err != nil {
+		output.Warning(fmt.Sprintf("%v; starting without AOT cache", err))
+		for _, line := range log {
+			output.Info(line)
+		}
+		os.Remove(cache)
+		current.Failed = err.Error()
+		h.writeAOTStamp(stampPath, current)
+		return nil
+	}
+	h.writeAOTStamp(stampPath, current)
+	output.SuccessWithMessage(fmt.Sprintf("generated %s", filepath.Base(cache)))
+	return flags
+}
+
+// aotCachePath resolves HYTALE_CACHE_DIR, the cache file, relative to DataDir
+func (h *HytaleManager) aotCachePath() string {
+	path := h.Config.GetString("HYTALE_CACHE_DIR", "Server/HytaleServer.aot")
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(h.DataDir, path)
+	}
+	return filepath.Clean(path)
+}
+
+// aotStamp describes the current server jar, Java runtime and JVM flags
+func (h *HytaleManager) aotStamp(jvmArgs []string) aotStamp {
+	stamp := aotStamp{
+		ServerVersion: h.installedVersion(),
+		JavaVersion:   javaVersion(),
+		JVMArgs:       strings.Join(jvmArgs, " "),
+	}
+	if info, err := os.Stat(h.serverJarPath); err == nil {
+		stamp.JarSize = info.Size()
+		stamp.JarModTime = info.ModTime().Unix()
+	}
+	return stamp
+}
+
+func (h *HytaleManager) writeAOTStamp(path string, stamp aotStamp) {
+	stamp.CheckedAtEpoch = time.Now().Unix()
+	if err := writeJSON(path, stamp); err != nil {
+		output.Warning(fmt.Sprintf("failed to record AOT cache state: %v", err))
+	}
+}
+
+// aotCacheUsable asks the JVM to load the cache with AOTMode=on, which fails
+// instead of silently ignoring a cache built for another jar or runtime
+func (h *HytaleManager) aotCacheUsable(cache string, jvmArgs []string) bool {
+	args := append([]string{}, jvmArgs...)
+	args = append(args, "-XX:AOTCache="+cache, "-XX:AOTMode=on", "-cp", h.serverJarPath, "-version")
+	cmd := exec.Command("java", args...)
+	cmd.Dir = h.BaseDir
+	return cmd.Run() == nil
+}
+
+// generateAOTCache records a cache with a training run of the server;
+// HYTALE_CACHE_TRAINING_ARGS should make it exit once it has booted. When the
+// run fails the last lines of its output are returned with the error.
+func (h *HytaleManager) generateAOTCache(cache string, jvmArgs []string) ([]string, error) {
+	if err := ensureDir(filepath.Dir(cache)); err != nil {
+		return nil, err
+	}
+	os.Remove(cache)
+
+	timeout := time.Duration(h.Config.GetInt("HYTALE_CACHE_TIMEOUT_MINUTES", 10)) * time.Minute
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	args := append([]string{}, jvmArgs...)
+	args = append(args, "-XX:AOTCacheOutput="+cache, "-jar", h.serverJarPath)
+	args = append(args, splitArgs(h.Config.GetString("HYTALE_CACHE_TRAINING_ARGS", "--bare --validate-assets --shutdown-after-validate"))...)
+	args = append(args, "--assets", h.assetsZipPath)
+
+	cmd := exec.CommandContext(ctx, "java", args...)
+	cmd.Dir = h.BaseDir
+	out, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return lastLines(out, aotLogLines), fmt.Errorf("AOT training run timed out after %s", timeout)
+	}
+	if err != nil {
+		return lastLines(out, aotLogLines), fmt.Errorf("AOT training run failed: %w", err)
+	}
+	if !fileExists(cache) {
+		return lastLines(out, aotLogLines), fmt.Errorf("AOT training run did not write %s", cache)
+	}
+	return nil, nil
+}
+
+// lastLines returns the last n non-empty lines of command output
+func lastLines(out []byte, n int) []string {
+	var lines []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimRight(line, "\r "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
+
+// javaVersion returns `java -version` on one line, which identifies the runtime build
+func javaVersion() string {
+	out, err := exec.Command("java", "-version").CombinedOutput()
+	if err != nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(string(out)), " ")
+}
+
+// versionLabel formats a possibly unknown version for messages
+func versionLabel(version string) string {
+	if version == "" {
+		return "unknown version"
+	}
+	return version
+}
@@ -0,0 +1,342 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/notify"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/output"
+)
+
+var (
+	authURLPattern  = regexp.MustCompile(`https?://[^\s"'<>]*(?:device|verify|activate|oauth)[^\s"'<>]*`)
+	authCodePattern = regexp.MustCompile(`(?i)(?:user[ _]code|code)\s*[:=]\s*([A-Z0-9]{4,}(?:-[A-Z0-9]{4,})*)`)
+)
+
+// credentialsPath is where hytale-downloader keeps its OAuth credentials (on the PVC)
+func (h *HytaleManager) credentialsPath() string {
+	return h.Config.GetString("HYTALE_DOWNLOADER_CREDENTIALS_PATH",
+		filepath.Join(h.DataDir, ".hytale-downloader-credentials.json"))
+}
+
+// runDownloaderCmd runs hytale-downloader without a TTY. If it starts a
+// device-code login, the verification URL and code are published as an event
+// instead of waiting on a prompt nobody can see. It returns the command's stdout.
+func (h *HytaleManager) runDownloaderCmd(echo bool, args ...string) (string, error) {
+	if err := h.prepareCredentials(); err != nil {
+		output.Warning(fmt.Sprintf("credentials: %v", err))
+	}
+
+	// A stalled run is stopped after HYTALE_DOWNLOADER_TIMEOUT_MINUTES; once
+	// a login prompt shows up, the login has HYTALE_AUTH_TIMEOUT_MINUTES
+	timeout := time.Duration(h.Config.GetInt("HYTALE_DOWNLOADER_TIMEOUT_MINUTES", 60)) * time.Minute
+	authTimeout := time.Duration(h.Config.GetInt("HYTALE_AUTH_TIMEOUT_MINUTES", 15)) * time.Minute
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	timer := time.AfterFunc(timeout, cancel)
+	defer timer.Stop()
+
+	watcher := &authPromptWatcher{
+		notifier: notify.New(h.Config),
+		onPrompt: func() {
+			timer.Reset(authTimeout)
+		},
+	}
+
+	args = append([]string{"-credentials-path", h.credentialsPath()}, args...)
+	cmd := exec.CommandContext(ctx, h.downloaderPath, args...)
+	cmd.Dir = h.DataDir
+
+	var stdout bytes.Buffer
+	if echo {
+		cmd.Stdout = io.MultiWriter(os.Stdout, &stdout, watcher)
+	} else {
+		cmd.Stdout = io.MultiWriter(&stdout, watcher)
+	}
+	cmd.Stderr = io.MultiWriter(os.Stderr, watcher)
+
+	err := cmd.Run()
+	if watcher.prompted() {
+		if err != nil {
+			watcher.notifier.Event("hytale.auth.failed", "Hytale downloader authorization was not completed", map[string]string{
+				"error": err.Error(),
+			})
+		} else {
+			watcher.notifier.Event("hytale.auth.completed", "Hytale downloader authorized", map[string]string{
+				"credentialsPath": h.credentialsPath(),
+			})
+		}
+	}
+	if ctx.Err() != nil {
+		if watcher.prompted() {
+			return stdout.String(), fmt.Errorf("authorization not completed within %s", authTimeout)
+		}
+		return stdout.String(), fmt.Errorf("hytale-downloader did not finish within %s", timeout)
+	}
+	return stdout.String(), err
+}
+
+// prepareCredentials seeds the PVC credentials from a mounted secret and
+// refreshes them when they have expired and a token endpoint is configured
+func (h *HytaleManager) prepareCredentials() error {
+	path := h.credentialsPath()
+
+	// Secrets are mounted read-only, so the downloader works on a copy
+	if src := h.Config.GetString("HYTALE_DOWNLOADER_CREDENTIALS_SRC", ""); src != "" && fileExists(src) {
+		secretCreds, err := readCredentials(src)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", src, err)
+		}
+		current, err := readCredentials(path)
+		if err != nil || secretCreds.expiry().After(current.expiry()) {
+			if err := secretCreds.write(path); err != nil {
+				return err
+			}
+		}
+	}
+
+	creds, err := readCredentials(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	// Without a token endpoint, expired credentials are left to the
+	// downloader, which asks for a new login
+	if h.Config.GetString("HYTALE_OAUTH_TOKEN_URL", "") == "" {
+		return nil
+	}
+	expiry := creds.expiry()
+	if expiry.IsZero() || time.Until(expiry) > 5*time.Minute {
+		return nil
+	}
+
+	output.Step("Refreshing Hytale credentials")
+	if err := h.refreshCredentials(creds); err != nil {
+		output.Warning(err.Error())
+		return nil
+	}
+	if err := creds.write(path); err != nil {
+		output.Error(err.Error())
+		return err
+	}
+	output.Success()
+	return nil
+}
+
+// refreshCredentials exchanges the refresh token for a new access token
+func (h *HytaleManager) refreshCredentials(creds credentials) error {
+	refreshToken := creds.get("refresh_token", "refreshToken")
+	if refreshToken == "" {
+		return fmt.Errorf("credentials expired and contain no refresh token")
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", refreshToken)
+	if clientID := h.Config.GetString("HYTALE_OAUTH_CLIENT_ID", ""); clientID != "" {
+		form.Set("client_id", clientID)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.PostForm(h.Config.GetString("HYTALE_OAUTH_TOKEN_URL", ""), form)
+	if err != nil {
+		return fmt.Errorf("token refresh failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("token refresh failed with status %d", resp.StatusCode)
+	}
+
+	var token struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return fmt.Errorf("invalid token response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return fmt.Errorf("token response contained no access token")
+	}
+
+	creds.set(token.AccessToken, "access_token", "accessToken")
+	if token.RefreshToken != "" {
+		creds.set(token.RefreshToken, "refresh_token", "refreshToken")
+	}
+	if token.ExpiresIn > 0 {
+		creds.setExpiry(time.Now().Add(time.Duration(token.ExpiresIn) * time.Second))
+	}
+	return nil
+}
+
+// credentials is the downloader's credentials file; unknown fields are kept as-is
+type credentials map[string]interface{}
+
+var expiryKeys = []string{"expires_at", "expiresAt", "expiry"}
+
+func readCredentials(path string) (credentials, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var creds credentials
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return nil, err
+	}
+	return creds, nil
+}
+
+// write saves the credentials atomically with owner-only permissions
+func (c credentials) write(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// get returns the first string value found under any of the keys
+func (c credentials) get(keys ...string) string {
+	for _, k := range keys {
+		if v, ok := c[k].(string); ok && v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// set stores v under whichever of the keys the file already uses
+func (c credentials) set(v string, keys ...string) {
+	for _, k := range keys {
+		if _, ok := c[k]; ok {
+			c[k] = v
+			return
+		}
+	}
+	c[keys[0]] = v
+}
+
+// expiry parses the access token expiry (unix seconds/milliseconds or RFC3339)
+func (c credentials) expiry() time.Time {
+	for _, k := range expiryKeys {
+		switch v := c[k].(type) {
+		case float64:
+			if v > 1e12 {
+				return time.UnixMilli(int64(v))
+			}
+			return time.Unix(int64(v), 0)
+		case string:
+			if t, err := time.Parse(time.RFC3339, v); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// setExpiry stores the expiry in the format the file already uses
+func (c credentials) setExpiry(t time.Time) {
+	for _, k := range expiryKeys {
+		switch c[k].(type) {
+		case float64:
+			c[k] = t.Unix()
+			return
+		case string:
+			c[k] = t.UTC().Format(time.RFC3339)
+			return
+		}
+	}
+	c[expiryKeys[0]] = t.Unix()
+}
+
+// authPromptWatcher scans downloader output for a device-code login prompt
+type authPromptWatcher struct {
+	notifier *notify.Notifier
+	onPrompt func()
+
+	mu      sync.Mutex
+	buf     []byte
+	url     string
+	code    string
+	emitted bool
+}
+
+func (w *authPromptWatcher) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexAny(w.buf, "\r\n")
+		if i < 0 {
+			break
+		}
+		w.scan(string(w.buf[:i]))
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// scan records the verification URL and code, publishing them once the URL is known
+func (w *authPromptWatcher) scan(line string) {
+	if m := authURLPattern.FindString(line); m != "" && w.url == "" {
+		w.url = m
+		if u, err := url.Parse(m); err == nil && w.code == "" {
+			w.code = u.Query().Get("user_code")
+		}
+	}
+	if m := authCodePattern.FindStringSubmatch(line); m != nil && w.code == "" {
+		w.code = m[1]
+	}
+
+	if w.emitted || w.url == "" {
+		return
+	}
+	// The code is usually printed right next to the URL; wait for it briefly
+	if w.code == "" && !strings.Contains(w.url, "user_code") {
+		return
+	}
+	w.emit()
+}
+
+func (w *authPromptWatcher) emit() {
+	w.emitted = true
+	w.notifier.Event("hytale.auth.required", "Hytale downloader needs authorization: open the URL and enter the code", map[string]string{
+		"url":  w.url,
+		"code": w.code,
+	})
+	if w.onPrompt != nil {
+		w.onPrompt()
+	}
+}
+
+// prompted reports whether a login prompt was seen (emitting it if the code never showed up)
+func (w *authPromptWatcher) prompted() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.emitted && w.url != "" {
+		w.emit()
+	}
+	return w.emitted
+}
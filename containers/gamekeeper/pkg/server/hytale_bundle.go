@@ -0,0 +1,167 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/curseforge"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/fsutil"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/output"
+)
+
+// Bundle layout:
+//
+//	build/       Server/ and Assets.zip
+//	downloader/  hytale-downloader
+//	curseforge/  CurseForge manifest and mod files
+//	mods/        mods installed from mods.yaml
+//	config/      rendered config.json
+const (
+	bundleBuildDir      = "build"
+	bundleDownloaderDir = "downloader"
+	bundleCurseForgeDir = "curseforge"
+	bundleModsDir       = "mods"
+	bundleConfigDir     = "config"
+)
+
+// CreateBundle writes the installed build, mods, downloader and config to dest
+func (h *HytaleManager) CreateBundle(dest string) error {
+	if err := h.validateBuild(); err != nil {
+		return fmt.Errorf("no complete installation to bundle: %w", err)
+	}
+	version := h.installedVersion()
+	if version == "" {
+		version = "unknown"
+	}
+
+	workDir, err := h.bundleWorkDir()
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+
+	output.Step("Collecting server files")
+	for _, entry := range hytaleBuildEntries {
+		if err := linkTree(filepath.Join(h.DataDir, entry), filepath.Join(workDir, bundleBuildDir, entry)); err != nil {
+			output.Error(err.Error())
+			return err
+		}
+	}
+	output.SuccessWithMessage(version)
+
+	parts := []struct {
+		name, src, dst string
+	}{
+		{"hytale-downloader", h.downloaderPath, filepath.Join(bundleDownloaderDir, "hytale-downloader")},
+		{"CurseForge manifest", filepath.Join(curseforge.StateDir(h.DataDir), "manifest.json"), filepath.Join(bundleCurseForgeDir, "manifest.json")},
+		{"CurseForge mods", filepath.Join(curseforge.StateDir(h.DataDir), "files"), filepath.Join(bundleCurseForgeDir, "files")},
+		{"Mods", filepath.Join(h.DataDir, "Mods"), bundleModsDir},
+		{"config.json", filepath.Join(h.BaseDir, "config.json"), filepath.Join(bundleConfigDir, "config.json")},
+	}
+	for _, p := range parts {
+		if !fileExists(p.src) {
+			continue
+		}
+		output.Step(fmt.Sprintf("Collecting %s", p.name))
+		if err := linkTree(p.src, filepath.Join(workDir, p.dst)); err != nil {
+			output.Error(err.Error())
+			return err
+		}
+		output.Success()
+	}
+
+	return h.writeBundle(dest, workDir, version)
+}
+
+// ImportBundle installs everything from a bundle made by CreateBundle. It
+// needs no network access; the build goes through the usual staged swap.
+func (h *HytaleManager) ImportBundle(src string) error {
+	workDir, manifest, err := h.openBundle(src)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+
+	if err := h.importBuild(filepath.Join(workDir, bundleBuildDir), manifest.Version); err != nil {
+		return err
+	}
+
+	if downloader := filepath.Join(workDir, bundleDownloaderDir, "hytale-downloader"); fileExists(downloader) {
+		output.Step("Installing hytale-downloader")
+		if err := fsutil.CopyFile(downloader, h.downloaderPath); err != nil {
+			output.Error(err.Error())
+			return err
+		}
+		os.Chmod(h.downloaderPath, 0755)
+		output.Success()
+	}
+
+	if cfDir := filepath.Join(workDir, bundleCurseForgeDir); fileExists(cfDir) {
+		output.Step("Installing CurseForge mods")
+		if err := copyDir(cfDir, curseforge.StateDir(h.DataDir)); err != nil {
+			output.Error(err.Error())
+			return err
+		}
+		linked, err := curseforge.Relink(h.Config, h.BaseDir, h.DataDir)
+		if err != nil {
+			output.Error(err.Error())
+			return err
+		}
+		output.SuccessWithMessage(fmt.Sprintf("%d mods", linked))
+	}
+
+	if modsDir := filepath.Join(workDir, bundleModsDir); fileExists(modsDir) {
+		output.Step("Installing mods")
+		if err := copyDir(modsDir, filepath.Join(h.DataDir, "Mods")); err != nil {
+			output.Error(err.Error())
+			return err
+		}
+		output.Success()
+	}
+
+	if cfg := filepath.Join(workDir, bundleConfigDir, "config.json"); fileExists(cfg) {
+		output.Step("Installing config.json")
+		if err := fsutil.CopyFile(cfg, filepath.Join(h.BaseDir, "config.json")); err != nil {
+			output.Error(err.Error())
+			return err
+		}
+		output.Success()
+	}
+
+	return nil
+}
+
+// importBuild swaps the bundled server files in as the live build
+func (h *HytaleManager) importBuild(buildDir, version string) error {
+	if err := newHytaleManager(h.Config, h.BaseDir, buildDir).validateBuild(); err != nil {
+		return fmt.Errorf("bundled build is invalid: %w", err)
+	}
+
+	if !h.stagedUpdatesEnabled() {
+		output.Step("Installing server files")
+		for _, entry := range hytaleBuildEntries {
+			live := filepath.Join(h.DataDir, entry)
+			if err := os.RemoveAll(live); err != nil {
+				output.Error(err.Error())
+				return err
+			}
+			if err := os.Rename(filepath.Join(buildDir, entry), live); err != nil {
+				output.Error(err.Error())
+				return err
+			}
+		}
+		output.SuccessWithMessage(version)
+		return h.buildHistory().SetCurrent(version)
+	}
+
+	stage := h.stagedInstall(h.DataDir, hytaleBuildEntries, nil)
+	if err := stage.Prepare(false); err != nil {
+		return err
+	}
+	if err := moveEntries(buildDir, stage.stagingDir); err != nil {
+		stage.Discard()
+		return err
+	}
+	return h.commitStaged(stage, version, h.worldPaths())
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/configfile"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/output"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/render"
+)
+
+// Configure renders config.json and applies it according to HYTALE_CONFIG_MODE:
+// seed-once, always-overwrite or managed-keys (the default), which merges
+// config changes without losing settings changed in-game
+func (h *HytaleManager) Configure() error {
+	output.Step("Rendering config.json")
+	mode, err := configfile.ParseMode(h.Config.GetString("HYTALE_CONFIG_MODE", string(configfile.ManagedKeys)))
+	if err != nil {
+		output.Error(err.Error())
+		return err
+	}
+
+	data, err := h.renderConfig()
+	if err != nil {
+		output.Error(err.Error())
+		return err
+	}
+
+	file := configfile.File{
+		Path:     filepath.Join(h.BaseDir, "config.json"),
+		Mode:     mode,
+		StateDir: filepath.Join(h.DataDir, stateDirName),
+		Perm:     0600,
+	}
+	result, err := file.Write(data)
+	if err != nil {
+		output.Error(err.Error())
+		return err
+	}
+	output.SuccessWithMessage(result)
+	return nil
+}
+
+// renderConfig renders HYTALE_CONFIG_TEMPLATE with the config values as the
+// "config" datasource and checks that the result is JSON
+func (h *HytaleManager) renderConfig() ([]byte, error) {
+	r := render.New(h.Config)
+	if h.Config.GetString("HYTALE_PASSWORD", "") == "" && h.Config.GetBool("HYTALE_PASSWORD_FROM_SECRET", false) {
+		password, err := h.secretPassword()
+		if err != nil {
+			return nil, err
+		}
+		values := h.Config.Values()
+		values["HYTALE_PASSWORD"] = password
+		r.Set("config", values)
+	}
+
+	tmpl := h.Config.GetString("HYTALE_CONFIG_TEMPLATE", "/usr/local/share/game-templates/serverconfig.template")
+	data, err := r.RenderFile(tmpl)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed interface{}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("rendered config.json is not valid JSON: %w", err)
+	}
+	return data, nil
+}
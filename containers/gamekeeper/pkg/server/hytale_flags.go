@@ -0,0 +1,93 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{12}$`)
+
+// hytaleOptions lists every server option gamekeeper passes, in the order
+// they appear on the command line. --assets and --bind are always set from
+// the data directory, SERVER_IP and SERVER_PORT.
+var hytaleOptions = []serverOption{
+	{Key: "HYTALE_ACCEPT_EARLY_PLUGINS", Flag: "--accept-early-plugins", Type: optionBool, Description: "Acknowledge that early plugins are unsupported and may cause instability"},
+	{Key: "HYTALE_ALLOW_OP", Flag: "--allow-op", Type: optionBool, Description: "Allow the owner to grant operator status"},
+	{Key: "HYTALE_AUTH_MODE", Flag: "--auth-mode", Type: optionEnum, Values: []string{"authenticated", "offline"}, Description: "Player authentication mode"},
+	{Key: "HYTALE_BACKUP", Flag: "--backup", Type: optionBool, Description: "Enable automatic world backups"},
+	{Key: "HYTALE_BACKUP_DIR", Flag: "--backup-dir", Type: optionString, Description: "Directory for backups"},
+	{Key: "HYTALE_BACKUP_FREQUENCY", Flag: "--backup-frequency", Type: optionInt, Description: "Minutes between backups"},
+	{Key: "HYTALE_BACKUP_MAX_COUNT", Flag: "--backup-max-count", Type: optionInt, Description: "Number of backups to keep"},
+	{Key: "HYTALE_BARE", Flag: "--bare", Type: optionBool, Description: "Run without loading worlds, binding ports or creating directories"},
+	{Key: "HYTALE_BOOT_COMMAND", Flag: "--boot-command", Type: optionString, Description: "Commands to run once the server has booted"},
+	{Key: "HYTALE_CLIENT_PID", Flag: "--client-pid", Type: optionInt, Description: "PID of the owning client process (singleplayer)"},
+	{Key: "HYTALE_DISABLE_ASSET_COMPARE", Flag: "--disable-asset-compare", Type: optionBool, Description: "Skip comparing assets against the base pack"},
+	{Key: "HYTALE_DISABLE_CPB_BUILD", Flag: "--disable-cpb-build", Type: optionBool, Description: "Skip building the compact prefab buffers"},
+	{Key: "HYTALE_DISABLE_FILE_WATCHER", Flag: "--disable-file-watcher", Type: optionBool, Description: "Don't watch asset and config files for changes"},
+	{Key: "HYTALE_DISABLE_SENTRY", Flag: "--disable-sentry", Type: optionBool, Description: "Disable crash reporting"},
+	{Key: "HYTALE_EARLY_PLUGINS", Flag: "--early-plugins", Type: optionString, Description: "Additional early plugin directory"},
+	{Key: "HYTALE_EVENT_DEBUG", Flag: "--event-debug", Type: optionBool, Description: "Log event dispatch for debugging"},
+	{Key: "HYTALE_FORCE_NETWORK_FLUSH", Flag: "--force-network-flush", Type: optionEnum, Values: []string{"true", "false"}, Description: "Flush network packets immediately"},
+	{Key: "HYTALE_GENERATE_SCHEMA", Flag: "--generate-schema", Type: optionBool, Description: "Generate config schema files and exit"},
+	{Key: "HYTALE_IDENTITY_TOKEN", Flag: "--identity-token", Type: optionString, Description: "Identity token for server authentication"},
+	{Key: "HYTALE_LOG", Flag: "--log", Type: optionString, Description: "Logger levels, e.g. root=INFO"},
+	{Key: "HYTALE_MIGRATE_WORLDS", Flag: "--migrate-worlds", Type: optionString, Description: "Worlds to migrate, comma-separated"},
+	{Key: "HYTALE_MIGRATIONS", Flag: "--migrations", Type: optionString, Description: "Migrations to run"},
+	{Key: "HYTALE_MODS", Flag: "--mods", Type: optionString, Description: "Additional mods directory"},
+	{Key: "HYTALE_OWNER_NAME", Flag: "--owner-name", Type: optionString, Description: "Name of the server owner"},
+	{Key: "HYTALE_OWNER_UUID", Flag: "--owner-uuid", Type: optionString, Description: "UUID of the server owner", validate: validateUUID},
+	{Key: "HYTALE_PREFAB_CACHE", Flag: "--prefab-cache", Type: optionString, Description: "Prefab cache directory"},
+	{Key: "HYTALE_SESSION_TOKEN", Flag: "--session-token", Type: optionString, Description: "Session token for server authentication"},
+	{Key: "HYTALE_SHUTDOWN_AFTER_VALIDATE", Flag: "--shutdown-after-validate", Type: optionBool, Description: "Exit after the validation options have run"},
+	{Key: "HYTALE_SINGLEPLAYER", Flag: "--singleplayer", Type: optionBool, Description: "Run as a singleplayer server"},
+	{Key: "HYTALE_TRANSPORT", Flag: "--transport", Type: optionEnum, Values: []string{"QUIC", "TCP"}, Description: "Network transport"},
+	{Key: "HYTALE_UNIVERSE", Flag: "--universe", Type: optionString, Description: "Universe (world data) directory"},
+	{Key: "HYTALE_VALIDATE_ASSETS", Flag: "--validate-assets", Type: optionBool, Description: "Validate assets on startup"},
+	{Key: "HYTALE_VALIDATE_PREFABS", Flag: "--validate-prefabs", Type: optionBoolOrString, Description: "Validate prefabs on startup, optionally with validation options"},
+	{Key: "HYTALE_VALIDATE_WORLD_GEN", Flag: "--validate-world-gen", Type: optionBool, Description: "Validate world generation on startup"},
+	{Key: "HYTALE_VERSION", Flag: "--version", Type: optionBool, Description: "Print the server version and exit"},
+	{Key: "HYTALE_WORLD_GEN", Flag: "--world-gen", Type: optionString, Description: "World generation directory"},
+}
+
+// serverOptionArgs builds the server options from config in table order,
+// reporting every invalid value at once
+func (h *HytaleManager) serverOptionArgs() ([]string, error) {
+	var args []string
+	var errs []error
+	for _, o := range hytaleOptions {
+		value, _ := configValue(h.Config.Get(o.Key))
+		arg, err := o.arg(strings.TrimSpace(value))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if arg != "" {
+			args = append(args, arg)
+		}
+	}
+	return args, errors.Join(errs...)
+}
+
+// OptionsMarkdown documents the server options as a Markdown table
+func (h *HytaleManager) OptionsMarkdown() string {
+	var b strings.Builder
+	b.WriteString("| Config key | Flag | Type | Description |\n")
+	b.WriteString("|---|---|---|---|\n")
+	for _, o := range hytaleOptions {
+		typ := string(o.Type)
+		if o.Type == optionEnum {
+			typ = strings.Join(o.Values, " \\| ")
+		}
+		fmt.Fprintf(&b, "| `%s` | `%s` | %s | %s |\n", o.Key, o.Flag, typ, o.Description)
+	}
+	return b.String()
+}
+
+func validateUUID(value string) error {
+	if !uuidPattern.MatchString(value) {
+		return fmt.Errorf("%q is not a UUID", value)
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/output"
+)
+
+// cgroupRoot is where the cgroup filesystem is mounted
+var cgroupRoot = "/sys/fs/cgroup"
+
+// jvmHeap returns -Xms/-Xmx sized from the container's memory limit, leaving
+// JVM_HEADROOM_PERCENT (default 25) for metaspace, threads and native memory.
+// Returns nil when JAVA_ARGS sets the heap itself, and fallback when there is
+// no limit or JVM_AUTO_HEAP is false.
+func (b *BaseManager) jvmHeap(fallback []string) []string {
+	output.Step("JVM heap")
+
+	if javaArgsSet(b.Config.GetString("JAVA_ARGS", ""), "-Xmx", "-Xms", "-XX:MaxRAMPercentage", "-XX:MaxRAM=") {
+		output.SuccessWithMessage("set by JAVA_ARGS")
+		return nil
+	}
+
+	limit := memoryLimit()
+	if limit <= 0 || !b.Config.GetBool("JVM_AUTO_HEAP", true) {
+		if len(fallback) == 0 {
+			output.SuccessWithMessage("JVM default (no container memory limit)")
+		} else {
+			output.SuccessWithMessage(fmt.Sprintf("%s (no container memory limit)", strings.Join(fallback, " ")))
+		}
+		return fallback
+	}
+
+	headroom := b.Config.GetInt("JVM_HEADROOM_PERCENT", 25)
+	if headroom < 0 || headroom > 90 {
+		output.Warning(fmt.Sprintf("JVM_HEADROOM_PERCENT %d out of range, using 25", headroom))
+		headroom = 25
+	}
+
+	limitMB := limit / (1024 * 1024)
+	heapMB := limitMB * int64(100-headroom) / 100
+	heap := []string{fmt.Sprintf("-Xms%dM", heapMB), fmt.Sprintf("-Xmx%dM", heapMB)}
+	output.SuccessWithMessage(fmt.Sprintf("%s (%dM limit, %d%% headroom)", strings.Join(heap, " "), limitMB, headroom))
+	return heap
+}
+
+// jvmGCFlags returns a game's GC flags unless JAVA_ARGS picks a collector
+func (b *BaseManager) jvmGCFlags(flags []string) []string {
+	if javaArgsSet(b.Config.GetString("JAVA_ARGS", ""), "-XX:+Use") {
+		return nil
+	}
+	return flags
+}
+
+// javaArgsSet reports whether any argument starts with one of the prefixes
+func javaArgsSet(javaArgs string, prefixes ...string) bool {
+	for _, arg := range splitArgs(javaArgs) {
+		for _, p := range prefixes {
+			if strings.HasPrefix(arg, p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// memoryLimit returns the cgroup v2 or v1 memory limit in bytes, or 0 if unlimited
+func memoryLimit() int64 {
+	var candidates []string
+
+	// cgroup v2: the process's own group, then the namespace root
+	if path := cgroupPath("0", ""); path != "" {
+		candidates = append(candidates, filepath.Join(cgroupRoot, path, "memory.max"))
+	}
+	candidates = append(candidates, filepath.Join(cgroupRoot, "memory.max"))
+
+	// cgroup v1
+	if path := cgroupPath("", "memory"); path != "" {
+		candidates = append(candidates, filepath.Join(cgroupRoot, "memory", path, "memory.limit_in_bytes"))
+	}
+	candidates = append(candidates, filepath.Join(cgroupRoot, "memory", "memory.limit_in_bytes"))
+
+	for _, path := range candidates {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		value := strings.TrimSpace(string(data))
+		if value == "max" {
+			return 0
+		}
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n <= 0 {
+			continue
+		}
+		// v1 reports "unlimited" as a huge page-aligned number
+		if n >= 1<<60 {
+			return 0
+		}
+		return n
+	}
+	return 0
+}
+
+// cgroupPath finds this process's cgroup from /proc/self/cgroup, by hierarchy
+// ID ("0" for v2) or by v1 controller name
+func cgroupPath(hierarchy, controller string) string {
+	f, err := os.Open("/proc/self/cgroup")
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if hierarchy != "" && parts[0] == hierarchy && parts[1] == "" {
+			return parts[2]
+		}
+		if controller != "" {
+			for _, c := range strings.Split(parts[1], ",") {
+				if c == controller {
+					return parts[2]
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// minecraftGCFlags are used unless MINECRAFT_AIKAR_FLAGS asks for the full set
+var minecraftGCFlags = []string{
+	"-XX:+UseG1GC",
+	"-XX:+ParallelRefProcEnabled",
+	"-XX:MaxGCPauseMillis=200",
+	"-XX:+DisableExplicitGC",
+}
+
+// hytaleGCFlags keep pauses short for the server's tick loop
+var hytaleGCFlags = []string{
+	"-XX:+UseG1GC",
+	"-XX:+ParallelRefProcEnabled",
+	"-XX:MaxGCPauseMillis=100",
+	"-XX:+DisableExplicitGC",
+}
+
+// maxHeap returns the last -Xmx value in args, as the JVM would use it
+func maxHeap(args []string) string {
+	heap := ""
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-Xmx") {
+			heap = strings.TrimPrefix(arg, "-Xmx")
+		}
+	}
+	return heap
+}
@@ -2,8 +2,11 @@ package server
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/kubelize/game-servers/gamekeeper/pkg/config"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/gamedef"
+	"gopkg.in/yaml.v3"
 )
 
 // Manager defines the interface for game server management
@@ -31,6 +34,32 @@ type Manager interface {
 
 	// Stop gracefully shuts down the game server
 	Stop() error
+
+	// Rollback restores a previously installed build (the newest one when
+	// version is empty) and pins it so updates don't replace it
+	Rollback(version string, restoreWorld bool) error
+
+	// Unpin allows updates to replace a rolled-back build again
+	Unpin() error
+}
+
+// Bundler is implemented by managers that can export their installation to an
+// offline bundle and install from one without network access
+type Bundler interface {
+	CreateBundle(dest string) error
+	ImportBundle(src string) error
+}
+
+// PlayerListSyncer is implemented by managers that maintain operator,
+// whitelist and ban lists from config
+type PlayerListSyncer interface {
+	SyncPlayerLists(live bool) error
+}
+
+// OptionDocumenter is implemented by managers whose server options come from
+// a declarative table, so they can be documented from it
+type OptionDocumenter interface {
+	OptionsMarkdown() string
 }
 
 // BaseManager provides common functionality for all game servers
@@ -41,23 +70,38 @@ type BaseManager struct {
 	DataDir  string
 }
 
-// NewManager creates a server manager for the specified game type
+// NewManager creates a server manager for a game name or alias from the
+// game registry
 func NewManager(gameType string, cfg *config.Config) (Manager, error) {
-	switch gameType {
-	case "hytale":
-		return NewHytaleManager(cfg), nil
-	case "conan-exiles", "ce":
-		return NewSteamManager(cfg, "conan-exiles", 443030), nil
-	case "seven-days-to-die", "sdtd":
-		return NewSteamManager(cfg, "sdtd", 294420), nil
-	case "palworld":
-		return NewSteamManager(cfg, "palworld", 2394010), nil
-	case "valheim":
-		return NewSteamManager(cfg, "valheim", 896660), nil
-	case "minecraft":
-		return NewMinecraftManager(cfg), nil
-	default:
-		return nil, fmt.Errorf("unsupported game type: %s", gameType)
+	games, err := Games(cfg.GetString("GAME_DEFINITIONS_DIR", gamedef.DefaultDir))
+	if err != nil {
+		return nil, err
+	}
+	game, err := LookupGame(games, gameType)
+	if err != nil {
+		return nil, err
+	}
+	return game.NewManager(cfg), nil
+}
+
+// AutoUpdateEnabled reports whether games are updated on start: AUTO_UPDATE,
+// which HYTALE_AUTO_UPDATE overrides for Hytale
+func AutoUpdateEnabled(cfg *config.Config, gameType string) bool {
+	enabled := cfg.GetBool("AUTO_UPDATE", true)
+	if gameType == "hytale" {
+		enabled = cfg.GetBool("HYTALE_AUTO_UPDATE", enabled)
+	}
+	return enabled
+}
+
+// withRoot returns a copy of the base manager rooted at dir, used to
+// inspect a staged build with the manager's own checks
+func (b *BaseManager) withRoot(dir string) *BaseManager {
+	return &BaseManager{
+		GameType: b.GameType,
+		Config:   b.Config,
+		BaseDir:  dir,
+		DataDir:  dir,
 	}
 }
 
@@ -70,3 +114,33 @@ func (b *BaseManager) ensureDirectories(dirs ...string) error {
 	}
 	return nil
 }
+
+// secretPassword reads ServerPassword from the chart's mounted password secret
+// (SERVER_PASSWORD_FILE)
+func (b *BaseManager) secretPassword() (string, error) {
+	path := b.Config.GetString("SERVER_PASSWORD_FILE", "/home/kubelize/config-data/serverpassword.yaml")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password secret: %w", err)
+	}
+
+	var secret struct {
+		ServerPassword string `yaml:"ServerPassword"`
+	}
+	if err := yaml.Unmarshal(data, &secret); err != nil {
+		return "", fmt.Errorf("failed to parse password secret %s: %w", path, err)
+	}
+	return secret.ServerPassword, nil
+}
+
+// serverPassword is SERVER_PASSWORD, or the ServerPassword of the chart's
+// password secret when that is mounted
+func (b *BaseManager) serverPassword() (string, error) {
+	if password, ok := configValue(b.Config.Get("SERVER_PASSWORD")); ok && password != "" {
+		return password, nil
+	}
+	if !fileExists(b.Config.GetString("SERVER_PASSWORD_FILE", "/home/kubelize/config-data/serverpassword.yaml")) {
+		return "", nil
+	}
+	return b.secretPassword()
+}
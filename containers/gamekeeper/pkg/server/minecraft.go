@@ -2,8 +2,19 @@ package server
 
 import (
 	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
 
+	"github.com/kubelize/game-servers/gamekeeper/pkg/archive"
 	"github.com/kubelize/game-servers/gamekeeper/pkg/config"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/gamedef"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/mcdist"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/modrinth"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/output"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/rcon"
 )
 
 // MinecraftManager manages Minecraft servers
@@ -11,6 +22,16 @@ type MinecraftManager struct {
 	*BaseManager
 }
 
+func init() {
+	Register(Game{
+		Name:         "minecraft",
+		DisplayName:  "Minecraft: Java Edition",
+		Aliases:      []string{"mc"},
+		Capabilities: []gamedef.Capability{gamedef.CapabilityMods, gamedef.CapabilityRCON, gamedef.CapabilityQuery},
+		Defaults:     map[string]string{"SERVER_PORT": "25565"},
+	}, func(cfg *config.Config) Manager { return NewMinecraftManager(cfg) })
+}
+
 func NewMinecraftManager(cfg *config.Config) *MinecraftManager {
 	baseDir := cfg.GetString("BASE_DIR", "/home/kubelize/server")
 	
@@ -29,32 +50,300 @@ func (m *MinecraftManager) Setup() error {
 }
 
 func (m *MinecraftManager) Update(force bool) error {
-	// TODO: Implement Minecraft server download
-	fmt.Println("  ℹ  Minecraft update not yet implemented")
+	installed := m.launchTargetExists()
+	if installed && !m.Config.GetBool("MINECRAFT_AUTO_UPDATE", true) && !force {
+		output.Step("Server files")
+		output.SuccessWithMessage("already downloaded (auto-update disabled)")
+		return nil
+	}
+
+	if installed && m.pinnedSkip() {
+		return nil
+	}
+
+	output.Step("Resolving version")
+	release, err := m.resolve(m.targetVersion())
+	if err != nil {
+		output.Error(err.Error())
+		if installed {
+			output.Warning("keeping installed server")
+			return nil
+		}
+		return err
+	}
+	output.SuccessWithMessage(release.ID())
+
+	if installed && !force && m.installedVersion() == release.ID() {
+		output.Step("Server files")
+		output.SuccessWithMessage(fmt.Sprintf("up to date (%s)", release.ID()))
+		return nil
+	}
+
+	if !m.stagedUpdatesEnabled() {
+		if err := m.installRelease(release, m.BaseDir); err != nil {
+			return err
+		}
+		return m.buildHistory().SetCurrent(release.ID())
+	}
+
+	// Forge keeps JVM settings in user_jvm_args.txt; an edited copy survives updates
+	var preserve []string
+	if fileExists(filepath.Join(m.BaseDir, "user_jvm_args.txt")) {
+		preserve = []string{"user_jvm_args.txt"}
+	}
+	stage := m.stagedInstall(m.BaseDir, nil, preserve)
+	if err := stage.Prepare(false); err != nil {
+		return err
+	}
+	if err := m.installRelease(release, stage.stagingDir); err != nil {
+		stage.Discard()
+		return err
+	}
+	if err := m.commitStaged(stage, release.ID(), m.worldPaths()); err != nil {
+		stage.Discard()
+		return err
+	}
 	return nil
 }
 
+// installRelease downloads a release into dir, running installer jars in server mode
+func (m *MinecraftManager) installRelease(release *mcdist.Release, dir string) error {
+	if !release.Installer {
+		output.Step(fmt.Sprintf("Downloading server %s", release.ID()))
+		if err := m.download(release.URL, filepath.Join(dir, m.launchJar(release.Type)), release.Checksum, ""); err != nil {
+			output.Error(err.Error())
+			return err
+		}
+		output.Success()
+		return nil
+	}
+
+	installer := filepath.Join(dir, path.Base(release.URL))
+	output.Step(fmt.Sprintf("Downloading installer %s", release.ID()))
+	if err := m.download(release.URL, installer, release.Checksum, ""); err != nil {
+		output.Error(err.Error())
+		return err
+	}
+	output.Success()
+
+	output.Step(fmt.Sprintf("Running %s installer", release.Type))
+	cmd := exec.Command("java", "-jar", installer, "--installServer", dir)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		output.Error(err.Error())
+		fmt.Println(string(out))
+		return fmt.Errorf("%s installer failed: %w", release.Type, err)
+	}
+	os.Remove(installer)
+	os.Remove(installer + ".log")
+	output.Success()
+	return nil
+}
+
+// CheckUpdate reports when the configured release differs from the installed
+// one, or when MINECRAFT_VERSION pins a game version older than the latest
 func (m *MinecraftManager) CheckUpdate() (bool, string, error) {
-	return false, "", nil
+	target, err := m.resolve(m.targetVersion())
+	if err != nil {
+		return false, "", err
+	}
+
+	installed := m.installedVersion()
+	if installed == "" {
+		return true, fmt.Sprintf("%s (installed version unknown)", target.ID()), nil
+	}
+	if target.ID() != installed {
+		return true, fmt.Sprintf("%s (installed: %s)", target.ID(), installed), nil
+	}
+
+	// Only release versions are compared; snapshots don't order cleanly
+	if mcdist.IsRelease(target.GameVersion) {
+		latest, err := m.resolve("latest")
+		if err == nil && archive.CompareVersions(latest.GameVersion, target.GameVersion) > 0 {
+			return true, fmt.Sprintf("%s (installed: %s, pinned by MINECRAFT_VERSION)", latest.ID(), installed), nil
+		}
+	}
+	return false, installed, nil
+}
+
+// resolve finds the MINECRAFT_TYPE release for a game version
+func (m *MinecraftManager) resolve(gameVersion string) (*mcdist.Release, error) {
+	resolver, err := mcdist.NewResolver(m.Config, m.serverType())
+	if err != nil {
+		return nil, err
+	}
+	return resolver.Resolve(gameVersion, m.Config.GetString("MINECRAFT_BUILD", "latest"))
+}
+
+// serverType is the configured server distribution (MINECRAFT_TYPE)
+func (m *MinecraftManager) serverType() string {
+	return strings.ToLower(m.Config.GetString("MINECRAFT_TYPE", mcdist.Vanilla))
+}
+
+// targetVersion is the configured version: an ID, "latest" or "latest-snapshot"
+func (m *MinecraftManager) targetVersion() string {
+	return m.Config.GetString("MINECRAFT_VERSION", "latest")
+}
+
+// installedVersion returns the release ID recorded when the server was installed
+func (m *MinecraftManager) installedVersion() string {
+	return m.buildHistory().Current().Version
+}
+
+// launchJar is the jar a downloaded (non-installer) distribution is saved as.
+// The Fabric launcher fetches the vanilla jar itself as server.jar, so it
+// needs a name of its own.
+func (m *MinecraftManager) launchJar(typ string) string {
+	if typ == mcdist.Fabric {
+		return "fabric-server-launch.jar"
+	}
+	return m.Config.GetString("SERVER_JAR", "server.jar")
+}
+
+// launchArgs returns the java arguments that start the installed release:
+// "-jar <jar>" for jar distributions, the args file the Forge/NeoForge
+// installer writes for run.sh, or the legacy Forge jar
+func (m *MinecraftManager) launchArgs() ([]string, error) {
+	typ, gameVersion, build := mcdist.ParseID(m.installedVersion())
+	if m.installedVersion() == "" {
+		typ = m.serverType()
+	}
+
+	var argsFile string
+	switch typ {
+	case mcdist.Forge:
+		argsFile = filepath.Join("libraries", "net", "minecraftforge", "forge", gameVersion+"-"+build, "unix_args.txt")
+	case mcdist.NeoForge:
+		argsFile = filepath.Join("libraries", "net", "neoforged", "neoforge", build, "unix_args.txt")
+	default:
+		jar := m.launchJar(typ)
+		if !fileExists(filepath.Join(m.BaseDir, jar)) {
+			return nil, fmt.Errorf("server jar not found: %s", filepath.Join(m.BaseDir, jar))
+		}
+		return []string{"-jar", jar}, nil
+	}
+
+	if fileExists(filepath.Join(m.BaseDir, argsFile)) {
+		return []string{"@" + argsFile}, nil
+	}
+
+	// Forge before 1.17 installs a runnable jar instead of an args file
+	matches, _ := filepath.Glob(filepath.Join(m.BaseDir, fmt.Sprintf("forge-%s-%s*.jar", gameVersion, build)))
+	for _, match := range matches {
+		if !strings.HasSuffix(match, "-installer.jar") {
+			return []string{"-jar", filepath.Base(match)}, nil
+		}
+	}
+	return nil, fmt.Errorf("%s launch files not found in %s (is it installed?)", typ, m.BaseDir)
+}
+
+// launchTargetExists reports whether an installed server can be launched
+func (m *MinecraftManager) launchTargetExists() bool {
+	_, err := m.launchArgs()
+	return err == nil
+}
+
+// worldPaths returns the world directories (relative to BaseDir); Bukkit-based
+// servers keep the nether and end in their own directories
+func (m *MinecraftManager) worldPaths() []string {
+	level := m.Config.GetString("LEVEL_NAME", "world")
+	return []string{level, level + "_nether", level + "_the_end"}
 }
 
 func (m *MinecraftManager) InstallMods() error {
-	fmt.Println("  ℹ  Minecraft mod management not yet implemented")
+	projects := m.Config.GetString("MINECRAFT_MODRINTH_PROJECTS", "")
+	if projects == "" {
+		output.Info("No mods configured")
+		return nil
+	}
+
+	installed := m.installedVersion()
+	if installed == "" {
+		return fmt.Errorf("install the server before its mods (no installed version recorded)")
+	}
+	typ, gameVersion, _ := mcdist.ParseID(installed)
+
+	loaders, modsDir := modrinthTarget(typ)
+	if loaders == nil {
+		output.Warning(fmt.Sprintf("Modrinth projects need a modded server (MINECRAFT_TYPE is %s), skipping", typ))
+		return nil
+	}
+	if dir := m.Config.GetString("MINECRAFT_MODS_PATH", ""); dir != "" {
+		modsDir = dir
+	}
+	if !filepath.IsAbs(modsDir) {
+		modsDir = filepath.Join(m.BaseDir, modsDir)
+	}
+
+	output.Step("Installing Modrinth projects")
+	output.SuccessWithMessage(fmt.Sprintf("%s %s", strings.Join(loaders, "/"), gameVersion))
+
+	mr, err := modrinth.NewManager(m.Config, modsDir, filepath.Join(m.DataDir, stateDirName, "modrinth"), loaders, gameVersion)
+	if err != nil {
+		return err
+	}
+	if err := mr.InstallMods(projects); err != nil {
+		return fmt.Errorf("Modrinth installation failed: %w", err)
+	}
 	return nil
 }
 
-func (m *MinecraftManager) Configure() error {
-	return nil
+// modrinthTarget returns the Modrinth loaders a server type can run and the
+// directory it loads them from
+func modrinthTarget(typ string) ([]string, string) {
+	switch typ {
+	case mcdist.Paper:
+		return []string{"paper", "spigot", "bukkit"}, "plugins"
+	case mcdist.Purpur:
+		return []string{"purpur", "paper", "spigot", "bukkit"}, "plugins"
+	case mcdist.Fabric, mcdist.Forge, mcdist.NeoForge:
+		return []string{typ}, "mods"
+	}
+	return nil, ""
 }
 
 func (m *MinecraftManager) Validate() error {
-	return nil
+	_, err := m.launchArgs()
+	return err
 }
 
 func (m *MinecraftManager) Start() error {
-	return fmt.Errorf("minecraft start not yet implemented")
+	launch, err := m.launchArgs()
+	if err != nil {
+		return err
+	}
+
+	// MIN_RAM/MAX_RAM pin the heap; otherwise it's sized from the memory limit
+	var args []string
+	if m.setting("MIN_RAM", "") != "" || m.setting("MAX_RAM", "") != "" {
+		args = []string{"-Xms" + m.setting("MIN_RAM", "1024M"), "-Xmx" + m.setting("MAX_RAM", "2048M")}
+	} else {
+		args = m.jvmHeap([]string{"-Xms1024M", "-Xmx2048M"})
+	}
+
+	javaArgs := splitArgs(m.Config.GetString("JAVA_ARGS", ""))
+	if m.Config.GetBool("MINECRAFT_AIKAR_FLAGS", false) {
+		args = append(args, aikarFlags(maxHeap(append(args, javaArgs...)))...)
+	} else {
+		args = append(args, m.jvmGCFlags(minecraftGCFlags)...)
+	}
+	args = append(args, javaArgs...)
+	args = append(args, fmt.Sprintf("-Duser.timezone=%s", m.Config.GetString("TZ", "UTC")))
+	args = append(args, launch...)
+	args = append(args, "nogui")
+
+	consolePort := m.Config.GetString("CONSOLE_PORT", "8080")
+	sessionName := m.Config.GetString("TMUX_SESSION_NAME", "minecraft-server")
+
+	return rcon.StartServerWithTmux(consolePort, sessionName, "java", args, m.BaseDir)
 }
 
 func (m *MinecraftManager) Stop() error {
 	return nil
 }
+
+// Rollback restores a previously installed server
+func (m *MinecraftManager) Rollback(version string, restoreWorld bool) error {
+	stage := m.stagedInstall(m.BaseDir, nil, nil)
+	return m.rollback(stage, version, restoreWorld, m.worldPaths())
+}
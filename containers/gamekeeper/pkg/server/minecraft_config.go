@@ -0,0 +1,227 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/output"
+)
+
+// minecraftProperty maps a server.properties key to its config key
+type minecraftProperty struct {
+	name      string
+	configKey string
+	def       string
+}
+
+// minecraftProperties are the server.properties keys managed from config.
+// Defaults only fill keys missing from the file; configured values always win.
+var minecraftProperties = []minecraftProperty{
+	{"server-port", "SERVER_PORT", "25565"},
+	{"server-ip", "SERVER_IP", ""},
+	{"online-mode", "ONLINE_MODE", "true"},
+	{"difficulty", "DIFFICULTY", "normal"},
+	{"hardcore", "HARDCORE", "false"},
+	{"max-players", "MAX_PLAYERS", "20"},
+	{"motd", "MOTD", "A Minecraft Server"},
+	{"level-name", "LEVEL_NAME", "world"},
+	{"level-seed", "LEVEL_SEED", ""},
+	{"level-type", "LEVEL_TYPE", "minecraft:normal"},
+	{"gamemode", "GAMEMODE", "survival"},
+	{"force-gamemode", "FORCE_GAMEMODE", "false"},
+	{"pvp", "PVP", "true"},
+	{"enable-command-block", "ENABLE_COMMAND_BLOCK", "false"},
+	{"spawn-protection", "SPAWN_PROTECTION", "16"},
+	{"view-distance", "VIEW_DISTANCE", "10"},
+	{"simulation-distance", "SIMULATION_DISTANCE", "10"},
+	{"white-list", "WHITE_LIST", "false"},
+	{"enforce-whitelist", "ENFORCE_WHITELIST", "false"},
+	{"allow-flight", "ALLOW_FLIGHT", "false"},
+	{"spawn-animals", "SPAWN_ANIMALS", "true"},
+	{"spawn-monsters", "SPAWN_MONSTERS", "true"},
+	{"spawn-npcs", "SPAWN_NPCS", "true"},
+	{"generate-structures", "GENERATE_STRUCTURES", "true"},
+	{"max-world-size", "MAX_WORLD_SIZE", "29999984"},
+	{"enable-rcon", "ENABLE_RCON", "false"},
+	{"rcon.port", "RCON_PORT", "25575"},
+	{"rcon.password", "RCON_PASSWORD", ""},
+	{"enable-query", "ENABLE_QUERY", "false"},
+	{"query.port", "QUERY_PORT", "25565"},
+	{"resource-pack", "RESOURCE_PACK", ""},
+	{"resource-pack-sha1", "RESOURCE_PACK_SHA1", ""},
+	{"require-resource-pack", "REQUIRE_RESOURCE_PACK", "false"},
+}
+
+func (m *MinecraftManager) Configure() error {
+	output.Step("Rendering server.properties")
+	if err := m.writeServerProperties(); err != nil {
+		output.Error(err.Error())
+		return err
+	}
+	output.Success()
+
+	// The server isn't running yet, so the lists are only written to disk
+	if err := m.SyncPlayerLists(false); err != nil {
+		return err
+	}
+
+	output.Step("Writing eula.txt")
+	if !m.Config.GetBool("EULA", false) {
+		output.Error("EULA not accepted")
+		return fmt.Errorf("the Minecraft EULA (https://aka.ms/MinecraftEULA) must be accepted: set EULA=true")
+	}
+	eula := "# https://aka.ms/MinecraftEULA\neula=true\n"
+	if err := os.WriteFile(filepath.Join(m.BaseDir, "eula.txt"), []byte(eula), 0644); err != nil {
+		output.Error(err.Error())
+		return fmt.Errorf("failed to write eula.txt: %w", err)
+	}
+	output.Success()
+	return nil
+}
+
+// writeServerProperties merges the configured properties into server.properties,
+// keeping the order, comments and unmanaged keys of an existing file
+func (m *MinecraftManager) writeServerProperties() error {
+	path := filepath.Join(m.BaseDir, "server.properties")
+
+	var lines []string
+	index := make(map[string]int)
+	if f, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if key, _, ok := strings.Cut(line, "="); ok && !strings.HasPrefix(strings.TrimSpace(line), "#") {
+				index[strings.TrimSpace(key)] = len(lines)
+			}
+			lines = append(lines, line)
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("failed to read server.properties: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	} else {
+		lines = append(lines, "# Minecraft server properties", "# Managed by gamekeeper; configured keys are overwritten on start")
+	}
+
+	for _, p := range minecraftProperties {
+		value, set := configValue(m.Config.Get(p.configKey))
+		i, exists := index[p.name]
+		if !set {
+			if exists {
+				continue
+			}
+			value = p.def
+		}
+
+		line := p.name + "=" + escapeProperty(value)
+		if exists {
+			lines[i] = line
+		} else {
+			index[p.name] = len(lines)
+			lines = append(lines, line)
+		}
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// escapeProperty escapes a value for a Java properties file; non-ASCII text
+// (common in MOTDs) is written as \u escapes so the encoding doesn't matter
+func escapeProperty(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == ' ' && i == 0:
+			b.WriteString(`\ `)
+		case r > 0x7e || r < 0x20:
+			for _, u := range utf16Units(r) {
+				fmt.Fprintf(&b, `\u%04x`, u)
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// utf16Units splits a rune into UTF-16 code units, as Java expects in \u escapes
+func utf16Units(r rune) []rune {
+	if r < 0x10000 {
+		return []rune{r}
+	}
+	r -= 0x10000
+	return []rune{0xd800 + (r>>10)&0x3ff, 0xdc00 + r&0x3ff}
+}
+
+// aikarFlags returns the G1 tuning recommended for Minecraft servers
+// (https://docs.papermc.io/paper/aikars-flags), with the large-heap variant
+// above 12GB
+func aikarFlags(maxHeap string) []string {
+	newSize, maxNewSize, regionSize, reserve, occupancy := "30", "40", "8M", "20", "15"
+	if heapMegabytes(maxHeap) > 12*1024 {
+		newSize, maxNewSize, regionSize, reserve, occupancy = "40", "50", "16M", "15", "20"
+	}
+
+	return []string{
+		"-XX:+UseG1GC",
+		"-XX:+ParallelRefProcEnabled",
+		"-XX:MaxGCPauseMillis=200",
+		"-XX:+UnlockExperimentalVMOptions",
+		"-XX:+DisableExplicitGC",
+		"-XX:+AlwaysPreTouch",
+		"-XX:G1NewSizePercent=" + newSize,
+		"-XX:G1MaxNewSizePercent=" + maxNewSize,
+		"-XX:G1HeapRegionSize=" + regionSize,
+		"-XX:G1ReservePercent=" + reserve,
+		"-XX:G1HeapWastePercent=5",
+		"-XX:G1MixedGCCountTarget=4",
+		"-XX:InitiatingHeapOccupancyPercent=" + occupancy,
+		"-XX:G1MixedGCLiveThresholdPercent=90",
+		"-XX:G1RSetUpdatingPauseTimePercent=5",
+		"-XX:SurvivorRatio=32",
+		"-XX:+PerfDisableSharedMem",
+		"-XX:MaxTenuringThreshold=1",
+		"-Dusing.aikars.flags=https://mcflags.emc.gs",
+		"-Daikars.new.flags=true",
+	}
+}
+
+// heapMegabytes parses a JVM heap size such as "2048M" or "12G"; 0 if invalid
+func heapMegabytes(size string) int64 {
+	size = strings.ToUpper(strings.TrimSpace(size))
+	if size == "" {
+		return 0
+	}
+
+	unit := size[len(size)-1]
+	n, err := strconv.ParseInt(strings.TrimRight(size, "KMGT"), 10, 64)
+	if err != nil {
+		return 0
+	}
+	switch unit {
+	case 'K':
+		return n / 1024
+	case 'M':
+		return n
+	case 'G':
+		return n * 1024
+	case 'T':
+		return n * 1024 * 1024
+	}
+	// Plain bytes
+	return n / (1024 * 1024)
+}
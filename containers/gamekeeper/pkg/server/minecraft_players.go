@@ -0,0 +1,439 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/mojang"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/output"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/rcon"
+)
+
+// Entries of ops.json, whitelist.json, banned-players.json and banned-ips.json
+type opEntry struct {
+	UUID                string `json:"uuid"`
+	Name                string `json:"name"`
+	Level               int    `json:"level"`
+	BypassesPlayerLimit bool   `json:"bypassesPlayerLimit"`
+}
+
+type whitelistEntry struct {
+	UUID string `json:"uuid"`
+	Name string `json:"name"`
+}
+
+type banEntry struct {
+	UUID    string `json:"uuid,omitempty"`
+	Name    string `json:"name,omitempty"`
+	IP      string `json:"ip,omitempty"`
+	Created string `json:"created"`
+	Source  string `json:"source"`
+	Expires string `json:"expires"`
+	Reason  string `json:"reason"`
+}
+
+// banTimeFormat is the timestamp format Minecraft writes in ban lists
+const banTimeFormat = "2006-01-02 15:04:05 -0700"
+
+// SyncPlayerLists writes ops, whitelist and ban lists from MINECRAFT_OPS,
+// MINECRAFT_WHITELIST, MINECRAFT_BANNED_PLAYERS and MINECRAFT_BANNED_IPS.
+// Lists that aren't configured are left alone. With live set, changes are
+// also sent to a running server over RCON.
+func (m *MinecraftManager) SyncPlayerLists(live bool) error {
+	online := m.Config.GetBool("ONLINE_MODE", true)
+	resolver := &uuidResolver{online: online, client: mojang.NewClient(m.Config), known: make(map[string]string)}
+
+	var existingOps []opEntry
+	var existingWhitelist []whitelistEntry
+	var existingBans, existingIPBans []banEntry
+	m.readPlayerList("ops.json", &existingOps)
+	m.readPlayerList("whitelist.json", &existingWhitelist)
+	m.readPlayerList("banned-players.json", &existingBans)
+	m.readPlayerList("banned-ips.json", &existingIPBans)
+	for _, e := range existingOps {
+		resolver.remember(e.Name, e.UUID)
+	}
+	for _, e := range existingWhitelist {
+		resolver.remember(e.Name, e.UUID)
+	}
+	for _, e := range existingBans {
+		resolver.remember(e.Name, e.UUID)
+	}
+
+	var commands []string
+	files := make(map[string]interface{})
+
+	if entries, ok := m.playerList("MINECRAFT_OPS"); ok {
+		defaultLevel := m.Config.GetInt("MINECRAFT_OP_LEVEL", 4)
+		ops := []opEntry{}
+		var levelErrs []error
+		for _, entry := range entries {
+			name, levelStr, hasLevel := strings.Cut(entry, "=")
+			name = strings.TrimSpace(name)
+			level := defaultLevel
+			if hasLevel {
+				n, err := strconv.Atoi(strings.TrimSpace(levelStr))
+				if err != nil || n < 1 || n > 4 {
+					// Skipping the entry would de-op the player
+					levelErrs = append(levelErrs, fmt.Errorf("invalid op level for %s: %s", name, levelStr))
+					continue
+				}
+				level = n
+			}
+			uuid, err := resolver.resolve(name)
+			if err != nil {
+				// Keep an existing op as is rather than de-opping them
+				if i := findOp(existingOps, name); i >= 0 {
+					output.Warning(fmt.Sprintf("%v; keeping the existing entry", err))
+					ops = append(ops, existingOps[i])
+				} else {
+					output.Warning(err.Error())
+				}
+				continue
+			}
+			ops = append(ops, opEntry{UUID: uuid, Name: name, Level: level})
+		}
+		if err := errors.Join(levelErrs...); err != nil {
+			return fmt.Errorf("MINECRAFT_OPS: %w", err)
+		}
+
+		var oldNames, newNames []string
+		for _, e := range existingOps {
+			oldNames = append(oldNames, e.Name)
+		}
+		for _, e := range ops {
+			newNames = append(newNames, e.Name)
+		}
+		added, removed := diffNames(oldNames, newNames)
+		for _, name := range added {
+			commands = append(commands, "op "+name)
+		}
+		for _, name := range removed {
+			commands = append(commands, "deop "+name)
+		}
+		files["ops.json"] = ops
+	}
+
+	if entries, ok := m.playerList("MINECRAFT_WHITELIST"); ok {
+		whitelist := []whitelistEntry{}
+		for _, name := range entries {
+			uuid, err := resolver.resolve(name)
+			if err != nil {
+				if i := findWhitelisted(existingWhitelist, name); i >= 0 {
+					output.Warning(fmt.Sprintf("%v; keeping the existing entry", err))
+					whitelist = append(whitelist, existingWhitelist[i])
+				} else {
+					output.Warning(err.Error())
+				}
+				continue
+			}
+			whitelist = append(whitelist, whitelistEntry{UUID: uuid, Name: name})
+		}
+
+		var oldNames, newNames []string
+		for _, e := range existingWhitelist {
+			oldNames = append(oldNames, e.Name)
+		}
+		for _, e := range whitelist {
+			newNames = append(newNames, e.Name)
+		}
+		added, removed := diffNames(oldNames, newNames)
+		for _, name := range added {
+			commands = append(commands, "whitelist add "+name)
+		}
+		for _, name := range removed {
+			commands = append(commands, "whitelist remove "+name)
+		}
+		files["whitelist.json"] = whitelist
+	}
+
+	if entries, ok := m.playerList("MINECRAFT_BANNED_PLAYERS"); ok {
+		created := make(map[string]string)
+		var oldNames []string
+		for _, e := range existingBans {
+			created[strings.ToLower(e.Name)] = e.Created
+			oldNames = append(oldNames, e.Name)
+		}
+
+		bans := []banEntry{}
+		var newNames []string
+		reasons := make(map[string]string)
+		for _, entry := range entries {
+			name, reason := banTarget(entry)
+			uuid, err := resolver.resolve(name)
+			if err != nil {
+				// Keep an existing ban rather than pardoning the player
+				if i := findBanned(existingBans, name); i >= 0 {
+					output.Warning(fmt.Sprintf("%v; keeping the existing entry", err))
+					bans = append(bans, existingBans[i])
+					newNames = append(newNames, existingBans[i].Name)
+				} else {
+					output.Warning(err.Error())
+				}
+				continue
+			}
+			bans = append(bans, newBanEntry(banEntry{UUID: uuid, Name: name, Reason: reason}, created[strings.ToLower(name)]))
+			newNames = append(newNames, name)
+			reasons[strings.ToLower(name)] = reason
+		}
+
+		added, removed := diffNames(oldNames, newNames)
+		for _, name := range added {
+			commands = append(commands, strings.TrimSpace("ban "+name+" "+reasons[strings.ToLower(name)]))
+		}
+		for _, name := range removed {
+			commands = append(commands, "pardon "+name)
+		}
+		files["banned-players.json"] = bans
+	}
+
+	if entries, ok := m.playerList("MINECRAFT_BANNED_IPS"); ok {
+		created := make(map[string]string)
+		var oldIPs []string
+		for _, e := range existingIPBans {
+			created[e.IP] = e.Created
+			oldIPs = append(oldIPs, e.IP)
+		}
+
+		bans := []banEntry{}
+		var newIPs []string
+		reasons := make(map[string]string)
+		for _, entry := range entries {
+			ip, reason := banTarget(entry)
+			if net.ParseIP(ip) == nil {
+				output.Warning(fmt.Sprintf("invalid IP address in MINECRAFT_BANNED_IPS: %s", ip))
+				continue
+			}
+			bans = append(bans, newBanEntry(banEntry{IP: ip, Reason: reason}, created[ip]))
+			newIPs = append(newIPs, ip)
+			reasons[strings.ToLower(ip)] = reason
+		}
+
+		added, removed := diffNames(oldIPs, newIPs)
+		for _, ip := range added {
+			commands = append(commands, strings.TrimSpace("ban-ip "+ip+" "+reasons[strings.ToLower(ip)]))
+		}
+		for _, ip := range removed {
+			commands = append(commands, "pardon-ip "+ip)
+		}
+		files["banned-ips.json"] = bans
+	}
+
+	if len(files) == 0 {
+		return nil
+	}
+
+	// The server rewrites its lists from memory after each command, so the
+	// commands go first and the files (with levels and reasons) are written last
+	if live && len(commands) > 0 {
+		m.applyLive(commands)
+	}
+
+	for _, name := range []string{"ops.json", "whitelist.json", "banned-players.json", "banned-ips.json"} {
+		list, ok := files[name]
+		if !ok {
+			continue
+		}
+		output.Step(fmt.Sprintf("Writing %s", name))
+		if err := writeJSON(filepath.Join(m.BaseDir, name), list); err != nil {
+			output.Error(err.Error())
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+		output.Success()
+	}
+	return nil
+}
+
+// applyLive sends commands to the running server; a server that isn't
+// running (or has RCON disabled) picks the lists up from disk on start
+func (m *MinecraftManager) applyLive(commands []string) {
+	output.Step("Applying changes over RCON")
+	if !m.Config.GetBool("ENABLE_RCON", false) {
+		output.SuccessWithMessage("skipped (ENABLE_RCON is false)")
+		return
+	}
+	password := m.setting("RCON_PASSWORD", "")
+	if password == "" {
+		output.SuccessWithMessage("skipped (no RCON_PASSWORD)")
+		return
+	}
+
+	addr := net.JoinHostPort("127.0.0.1", m.setting("RCON_PORT", "25575"))
+	client, err := rcon.Dial(addr, password, 5*time.Second)
+	if err != nil {
+		output.SuccessWithMessage(fmt.Sprintf("server not reachable (%v), files only", err))
+		return
+	}
+	defer client.Close()
+
+	for _, cmd := range commands {
+		if _, err := client.Command(cmd); err != nil {
+			output.Error(fmt.Sprintf("%s: %v", cmd, err))
+			return
+		}
+	}
+	output.SuccessWithMessage(fmt.Sprintf("%d command(s)", len(commands)))
+}
+
+// playerList reads a configured list: a YAML list, or a string of entries
+// separated by commas or newlines. ok is false when the key isn't set.
+func (m *MinecraftManager) playerList(key string) ([]string, bool) {
+	raw := m.Config.Get(key)
+	if raw == nil {
+		return nil, false
+	}
+
+	var items []string
+	switch v := raw.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := configValue(item); ok {
+				items = append(items, s)
+			}
+		}
+	default:
+		s, _ := configValue(v)
+		items = strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '\n' })
+	}
+
+	var entries []string
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			entries = append(entries, item)
+		}
+	}
+	return entries, true
+}
+
+// readPlayerList loads an existing list file; a missing or invalid file reads as empty
+func (m *MinecraftManager) readPlayerList(name string, v interface{}) {
+	data, err := os.ReadFile(filepath.Join(m.BaseDir, name))
+	if err != nil {
+		return
+	}
+	json.Unmarshal(data, v)
+}
+
+// setting returns a config value as a string, accepting YAML numbers and booleans
+func (m *MinecraftManager) setting(key, def string) string {
+	if v, ok := configValue(m.Config.Get(key)); ok && v != "" {
+		return v
+	}
+	return def
+}
+
+// uuidResolver maps player names to UUIDs: offline UUIDs are derived from the
+// name, online ones come from existing lists or the profile API
+type uuidResolver struct {
+	online bool
+	client *mojang.Client
+	known  map[string]string
+}
+
+// remember records a UUID from an existing list if it fits the current mode;
+// online UUIDs are version 4, offline ones version 3
+func (r *uuidResolver) remember(name, uuid string) {
+	if name == "" || len(uuid) != 36 {
+		return
+	}
+	if (uuid[14] == '4') == r.online {
+		r.known[strings.ToLower(name)] = uuid
+	}
+}
+
+func (r *uuidResolver) resolve(name string) (string, error) {
+	if !r.online {
+		return mojang.OfflineUUID(name), nil
+	}
+	if uuid, ok := r.known[strings.ToLower(name)]; ok {
+		return uuid, nil
+	}
+
+	uuid, err := r.client.LookupUUID(name)
+	if err != nil {
+		if errors.Is(err, mojang.ErrUnknownPlayer) {
+			return "", fmt.Errorf("skipping %s: no such Minecraft account", name)
+		}
+		return "", fmt.Errorf("skipping %s: %v", name, err)
+	}
+	r.known[strings.ToLower(name)] = uuid
+	return uuid, nil
+}
+
+// banTarget splits a "target=reason" ban entry
+func banTarget(entry string) (string, string) {
+	target, reason, _ := strings.Cut(entry, "=")
+	return strings.TrimSpace(target), strings.TrimSpace(reason)
+}
+
+// newBanEntry fills in the fields Minecraft expects, keeping the original ban time
+func newBanEntry(e banEntry, created string) banEntry {
+	if created == "" {
+		created = time.Now().UTC().Format(banTimeFormat)
+	}
+	e.Created = created
+	e.Source = "gamekeeper"
+	e.Expires = "forever"
+	if e.Reason == "" {
+		e.Reason = "Banned by an operator."
+	}
+	return e
+}
+
+// findOp, findWhitelisted and findBanned return the index of a player's
+// existing entry, or -1
+func findOp(entries []opEntry, name string) int {
+	for i, e := range entries {
+		if strings.EqualFold(e.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+func findWhitelisted(entries []whitelistEntry, name string) int {
+	for i, e := range entries {
+		if strings.EqualFold(e.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+func findBanned(entries []banEntry, name string) int {
+	for i, e := range entries {
+		if strings.EqualFold(e.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// diffNames returns the names only in next (added) and only in prev (removed),
+// compared case-insensitively like Minecraft does
+func diffNames(prev, next []string) (added, removed []string) {
+	inPrev := make(map[string]bool)
+	for _, n := range prev {
+		inPrev[strings.ToLower(n)] = true
+	}
+	inNext := make(map[string]bool)
+	for _, n := range next {
+		inNext[strings.ToLower(n)] = true
+		if !inPrev[strings.ToLower(n)] {
+			added = append(added, n)
+		}
+	}
+	for _, n := range prev {
+		if !inNext[strings.ToLower(n)] {
+			removed = append(removed, n)
+		}
+	}
+	return added, removed
+}
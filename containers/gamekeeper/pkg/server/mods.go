@@ -0,0 +1,105 @@
+package server
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/archive"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/config"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/fsutil"
+)
+
+// installConfiguredMod installs a mod from mods.yaml below root: the file is
+// downloaded, its checksum verified, archives are extracted into installPath
+// (plain files are copied there) and the listed config files are placed.
+func (b *BaseManager) installConfiguredMod(mod config.ModConfig, root string) error {
+	if mod.URL == "" {
+		return fmt.Errorf("no url configured")
+	}
+
+	installDir, err := resolveBelow(root, mod.InstallPath, "Mods")
+	if err != nil {
+		return err
+	}
+
+	workDir := filepath.Join(b.DataDir, stateDirName, "mods", safeName(mod.Name))
+	if err := os.RemoveAll(workDir); err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+
+	// Downloads live outside workDir so an interrupted one resumes next time
+	fileName := modFileName(mod.URL)
+	downloaded := filepath.Join(b.DataDir, stateDirName, "downloads", safeName(mod.Name)+"-"+fileName)
+	if err := b.download(mod.URL, downloaded, mod.Checksum, ""); err != nil {
+		return fmt.Errorf("download failed: %w", err)
+	}
+	defer os.Remove(downloaded)
+
+	// Archives are unpacked first so config files can be taken from them
+	contentDir := filepath.Join(workDir, "content")
+	if archive.IsArchive(fileName) {
+		if err := archive.Extract(downloaded, contentDir, archive.Options{}); err != nil {
+			return err
+		}
+		if err := copyDir(contentDir, installDir); err != nil {
+			return err
+		}
+	} else {
+		if err := ensureDir(installDir); err != nil {
+			return err
+		}
+		if err := fsutil.CopyFile(downloaded, filepath.Join(installDir, fileName)); err != nil {
+			return err
+		}
+	}
+
+	for _, cf := range mod.ConfigFiles {
+		src, err := resolveBelow(contentDir, cf.Source, "")
+		if err != nil {
+			return err
+		}
+		dest, err := resolveBelow(root, cf.Destination, "")
+		if err != nil {
+			return err
+		}
+		if !fileExists(src) {
+			return fmt.Errorf("config file %s not found in mod", cf.Source)
+		}
+		if err := ensureDir(filepath.Dir(dest)); err != nil {
+			return err
+		}
+		if err := fsutil.CopyFile(src, dest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// modFileName derives a local file name from a download URL
+func modFileName(rawURL string) string {
+	name := path.Base(rawURL)
+	if u, err := url.Parse(rawURL); err == nil {
+		name = path.Base(u.Path)
+	}
+	if name == "" || name == "." || name == "/" {
+		return "mod"
+	}
+	return name
+}
+
+// resolveBelow joins rel onto root, rejecting paths that escape it
+func resolveBelow(root, rel, fallback string) (string, error) {
+	if rel == "" {
+		rel = fallback
+	}
+	p := filepath.Join(root, filepath.FromSlash(rel))
+	if p != filepath.Clean(root) && !strings.HasPrefix(p, filepath.Clean(root)+string(os.PathSeparator)) {
+		return "", fmt.Errorf("path %s is outside %s", rel, root)
+	}
+	return p, nil
+}
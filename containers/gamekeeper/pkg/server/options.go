@@ -0,0 +1,87 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// optionType is how a config value maps to a command-line flag or setting
+type optionType string
+
+const (
+	// optionBool adds the bare flag when the value is true
+	optionBool optionType = "bool"
+	// optionString passes the value as is
+	optionString optionType = "string"
+	// optionInt passes a non-negative integer
+	optionInt optionType = "int"
+	// optionEnum passes one of a fixed set of values
+	optionEnum optionType = "enum"
+	// optionBoolOrString adds the bare flag for true, or passes any other value
+	optionBoolOrString optionType = "bool or string"
+	// optionFloat is a decimal number
+	optionFloat optionType = "float"
+	// optionList is a comma-separated list of values
+	optionList optionType = "list"
+)
+
+// serverOption maps a config key to a server command-line option
+type serverOption struct {
+	Key         string
+	Flag        string
+	Type        optionType
+	Values      []string
+	Description string
+	validate    func(string) error
+}
+
+// arg returns the command-line token for a value, or "" when the option is
+// off or unset. Values are passed as one flag=value token so spaces and
+// quotes reach the server unchanged.
+func (o serverOption) arg(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	switch o.Type {
+	case optionBool:
+		on, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("%s: %q is not a boolean", o.Key, value)
+		}
+		if !on {
+			return "", nil
+		}
+		return o.Flag, nil
+	case optionBoolOrString:
+		if on, err := strconv.ParseBool(value); err == nil {
+			if !on {
+				return "", nil
+			}
+			return o.Flag, nil
+		}
+	case optionInt:
+		if n, err := strconv.Atoi(value); err != nil || n < 0 {
+			return "", fmt.Errorf("%s: %q is not a non-negative integer", o.Key, value)
+		}
+	case optionEnum:
+		canonical := ""
+		for _, v := range o.Values {
+			if strings.EqualFold(v, value) {
+				canonical = v
+			}
+		}
+		if canonical == "" {
+			return "", fmt.Errorf("%s: %q is not one of %s", o.Key, value, strings.Join(o.Values, ", "))
+		}
+		value = canonical
+	}
+
+	if o.validate != nil {
+		if err := o.validate(value); err != nil {
+			return "", fmt.Errorf("%s: %w", o.Key, err)
+		}
+	}
+	return o.Flag + "=" + value, nil
+}
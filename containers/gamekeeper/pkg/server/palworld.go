@@ -0,0 +1,199 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/output"
+)
+
+// palworldFlags are the launch options taken from config, after -port,
+// -players and the performance flags
+var palworldFlags = []serverOption{
+	{Key: "PALWORLD_COMMUNITY", Flag: "-publiclobby", Type: optionBool, Description: "List the server in the community server browser"},
+	{Key: "PALWORLD_WORKER_THREADS", Flag: "-NumberOfWorkerThreadsServer", Type: optionInt, Description: "Number of server worker threads"},
+}
+
+// PalworldManager manages Palworld servers. The palworld definition installs
+// the server; the manager writes the OptionSettings of PalWorldSettings.ini
+// from config and builds the launch flags.
+type PalworldManager struct {
+	*DefinitionManager
+}
+
+func init() {
+	RegisterDefinition("palworld", func(d *DefinitionManager) Manager { return NewPalworldManager(d) })
+}
+
+func NewPalworldManager(d *DefinitionManager) *PalworldManager {
+	return &PalworldManager{DefinitionManager: d}
+}
+
+// Configure renders the definition's templates, then writes the settings
+// config sets into PalWorldSettings.ini
+func (p *PalworldManager) Configure() error {
+	if err := p.DefinitionManager.Configure(); err != nil {
+		return err
+	}
+
+	output.Step("Writing PalWorldSettings.ini")
+	names, values, err := p.palworldSettingValues()
+	if err != nil {
+		output.Error("invalid settings")
+		return err
+	}
+
+	path := p.settingsPath()
+	current, err := os.ReadFile(path)
+	exists := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		output.Error(err.Error())
+		return err
+	}
+	// The server starts with an empty settings file; the defaults ship
+	// alongside it
+	seed, _ := os.ReadFile(filepath.Join(p.BaseDir, "DefaultPalWorldSettings.ini"))
+
+	data, changed, err := updateOptionSettings(current, seed, names, values)
+	if err != nil {
+		err = fmt.Errorf("failed to update %s: %w", path, err)
+		output.Error(err.Error())
+		return err
+	}
+	if exists && string(data) == string(current) {
+		output.SuccessWithMessage("up to date")
+		return nil
+	}
+
+	if err := ensureDir(filepath.Dir(path)); err != nil {
+		output.Error(err.Error())
+		return err
+	}
+	// The file holds the admin and server passwords
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		output.Error(err.Error())
+		return err
+	}
+	output.SuccessWithMessage(fmt.Sprintf("%d setting(s) from config", changed))
+	return nil
+}
+
+// Validate checks the installed build, the command line, the settings and
+// the RCON and REST API ports
+func (p *PalworldManager) Validate() error {
+	errs := []error{p.DefinitionManager.Validate()}
+	if _, _, err := p.palworldSettingValues(); err != nil {
+		errs = append(errs, fmt.Errorf("invalid settings:\n%w", err))
+	}
+	if _, err := p.launchArgs(); err != nil {
+		errs = append(errs, fmt.Errorf("invalid launch options:\n%w", err))
+	}
+
+	rcon, err := configFlag(p.Config, "PALWORLD_RCON_ENABLED", false)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	rest, err := configFlag(p.Config, "PALWORLD_REST_API_ENABLED", false)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if rcon && rest && p.port("rcon") == p.port("rest") {
+		errs = append(errs, fmt.Errorf("RCON_PORT and REST_API_PORT are both %d", p.port("rest")))
+	}
+	if (rcon || rest) && p.Config.Get("ADMIN_PASSWORD") == nil {
+		output.Warning("RCON and the REST API authenticate with AdminPassword; set ADMIN_PASSWORD")
+	}
+	return errors.Join(errs...)
+}
+
+func (p *PalworldManager) Start() error {
+	command, args, err := p.commandLine()
+	if err != nil {
+		return err
+	}
+	launchArgs, err := p.launchArgs()
+	if err != nil {
+		return err
+	}
+	return p.launch(command, append(args, launchArgs...), filepath.Join(p.BaseDir, "console.log"))
+}
+
+// launchArgs builds PalServer.sh's flags: the game port, MAX_PLAYERS (default
+// 32), the multithreading flags unless PALWORLD_PERF_THREADS is false, and the
+// options in palworldFlags
+func (p *PalworldManager) launchArgs() ([]string, error) {
+	var errs []error
+	players := p.Config.GetInt("MAX_PLAYERS", 32)
+	if players < 1 {
+		errs = append(errs, fmt.Errorf("MAX_PLAYERS must be at least 1"))
+	}
+	args := []string{
+		fmt.Sprintf("-port=%d", p.port("game")),
+		"-players=" + strconv.Itoa(players),
+	}
+
+	perf, err := configFlag(p.Config, "PALWORLD_PERF_THREADS", true)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if perf {
+		args = append(args, "-useperfthreads", "-NoAsyncLoadingThread", "-UseMultithreadForDS")
+	}
+
+	for _, o := range palworldFlags {
+		value, _ := configValue(p.Config.Get(o.Key))
+		arg, err := o.arg(strings.TrimSpace(value))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if arg != "" {
+			args = append(args, arg)
+		}
+	}
+	return args, errors.Join(errs...)
+}
+
+// port returns a port of the definition after config overrides
+func (p *PalworldManager) port(name string) int {
+	for _, port := range p.Ports() {
+		if port.Name == name {
+			return port.Port
+		}
+	}
+	return 0
+}
+
+func (p *PalworldManager) settingsPath() string {
+	return filepath.Join(p.BaseDir, "Pal", "Saved", "Config", "LinuxServer", "PalWorldSettings.ini")
+}
+
+// OptionsMarkdown documents the settings and launch options as Markdown tables
+func (p *PalworldManager) OptionsMarkdown() string {
+	var b strings.Builder
+	b.WriteString("## PalWorldSettings.ini\n\n")
+	b.WriteString("| Config key | Setting | Type | Description |\n")
+	b.WriteString("|---|---|---|---|\n")
+	for _, s := range palworldSettings {
+		typ := string(s.Type)
+		if len(s.Values) > 0 {
+			typ = strings.Join(s.Values, " \\| ")
+		}
+		fmt.Fprintf(&b, "| `%s` | `%s` | %s | %s |\n", s.Key, s.Name, typ, s.Description)
+	}
+
+	b.WriteString("\n## Launch options\n\n")
+	b.WriteString("| Config key | Flag | Type | Description |\n")
+	b.WriteString("|---|---|---|---|\n")
+	b.WriteString("| `SERVER_PORT` | `-port` | int | Game port |\n")
+	b.WriteString("| `MAX_PLAYERS` | `-players` | int | Maximum number of players |\n")
+	b.WriteString("| `PALWORLD_PERF_THREADS` | `-useperfthreads -NoAsyncLoadingThread -UseMultithreadForDS` | bool | Multithreading flags, on by default |\n")
+	for _, o := range palworldFlags {
+		fmt.Fprintf(&b, "| `%s` | `%s` | %s | %s |\n", o.Key, o.Flag, o.Type, o.Description)
+	}
+	return b.String()
+}
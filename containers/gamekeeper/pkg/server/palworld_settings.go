@@ -0,0 +1,370 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// palworldSetting maps a config key to a setting in the OptionSettings of
+// PalWorldSettings.ini
+type palworldSetting struct {
+	Key         string
+	Name        string
+	Type        optionType
+	Values      []string
+	Description string
+}
+
+// palworldSettings lists the documented settings of PalWorldSettings.ini.
+// Settings config doesn't set keep the value in the file.
+var palworldSettings = []palworldSetting{
+	{Key: "PALWORLD_DIFFICULTY", Name: "Difficulty", Type: optionEnum, Values: []string{"None", "Normal", "Difficult"}, Description: "Difficulty preset; None uses the individual rates"},
+	{Key: "PALWORLD_RANDOMIZER_TYPE", Name: "RandomizerType", Type: optionEnum, Values: []string{"None", "Region", "All"}, Description: "Pal spawn randomizer"},
+	{Key: "PALWORLD_RANDOMIZER_SEED", Name: "RandomizerSeed", Type: optionString, Description: "Randomizer seed"},
+	{Key: "PALWORLD_IS_RANDOMIZER_PAL_LEVEL_RANDOM", Name: "bIsRandomizerPalLevelRandom", Type: optionBool, Description: "Randomize Pal levels as well"},
+	{Key: "PALWORLD_DAY_TIME_SPEED_RATE", Name: "DayTimeSpeedRate", Type: optionFloat, Description: "Day time speed"},
+	{Key: "PALWORLD_NIGHT_TIME_SPEED_RATE", Name: "NightTimeSpeedRate", Type: optionFloat, Description: "Night time speed"},
+	{Key: "PALWORLD_EXP_RATE", Name: "ExpRate", Type: optionFloat, Description: "Experience gain rate"},
+	{Key: "PALWORLD_PAL_CAPTURE_RATE", Name: "PalCaptureRate", Type: optionFloat, Description: "Pal capture rate"},
+	{Key: "PALWORLD_PAL_SPAWN_NUM_RATE", Name: "PalSpawnNumRate", Type: optionFloat, Description: "Pal spawn rate"},
+	{Key: "PALWORLD_PAL_DAMAGE_RATE_ATTACK", Name: "PalDamageRateAttack", Type: optionFloat, Description: "Damage dealt by Pals"},
+	{Key: "PALWORLD_PAL_DAMAGE_RATE_DEFENSE", Name: "PalDamageRateDefense", Type: optionFloat, Description: "Damage taken by Pals"},
+	{Key: "PALWORLD_PLAYER_DAMAGE_RATE_ATTACK", Name: "PlayerDamageRateAttack", Type: optionFloat, Description: "Damage dealt by players"},
+	{Key: "PALWORLD_PLAYER_DAMAGE_RATE_DEFENSE", Name: "PlayerDamageRateDefense", Type: optionFloat, Description: "Damage taken by players"},
+	{Key: "PALWORLD_PLAYER_STOMACH_DECREACE_RATE", Name: "PlayerStomachDecreaceRate", Type: optionFloat, Description: "Player hunger rate"},
+	{Key: "PALWORLD_PLAYER_STAMINA_DECREACE_RATE", Name: "PlayerStaminaDecreaceRate", Type: optionFloat, Description: "Player stamina drain rate"},
+	{Key: "PALWORLD_PLAYER_AUTO_HP_REGENE_RATE", Name: "PlayerAutoHPRegeneRate", Type: optionFloat, Description: "Player health regeneration rate"},
+	{Key: "PALWORLD_PLAYER_AUTO_HP_REGENE_RATE_IN_SLEEP", Name: "PlayerAutoHpRegeneRateInSleep", Type: optionFloat, Description: "Player health regeneration rate while sleeping"},
+	{Key: "PALWORLD_PAL_STOMACH_DECREACE_RATE", Name: "PalStomachDecreaceRate", Type: optionFloat, Description: "Pal hunger rate"},
+	{Key: "PALWORLD_PAL_STAMINA_DECREACE_RATE", Name: "PalStaminaDecreaceRate", Type: optionFloat, Description: "Pal stamina drain rate"},
+	{Key: "PALWORLD_PAL_AUTO_HP_REGENE_RATE", Name: "PalAutoHPRegeneRate", Type: optionFloat, Description: "Pal health regeneration rate"},
+	{Key: "PALWORLD_PAL_AUTO_HP_REGENE_RATE_IN_SLEEP", Name: "PalAutoHpRegeneRateInSleep", Type: optionFloat, Description: "Pal health regeneration rate in the Palbox"},
+	{Key: "PALWORLD_BUILD_OBJECT_HP_RATE", Name: "BuildObjectHpRate", Type: optionFloat, Description: "Structure health"},
+	{Key: "PALWORLD_BUILD_OBJECT_DAMAGE_RATE", Name: "BuildObjectDamageRate", Type: optionFloat, Description: "Damage to structures"},
+	{Key: "PALWORLD_BUILD_OBJECT_DETERIORATION_DAMAGE_RATE", Name: "BuildObjectDeteriorationDamageRate", Type: optionFloat, Description: "Structure deterioration rate"},
+	{Key: "PALWORLD_COLLECTION_DROP_RATE", Name: "CollectionDropRate", Type: optionFloat, Description: "Gatherable item drop rate"},
+	{Key: "PALWORLD_COLLECTION_OBJECT_HP_RATE", Name: "CollectionObjectHpRate", Type: optionFloat, Description: "Gatherable object health"},
+	{Key: "PALWORLD_COLLECTION_OBJECT_RESPAWN_SPEED_RATE", Name: "CollectionObjectRespawnSpeedRate", Type: optionFloat, Description: "Gatherable object respawn interval"},
+	{Key: "PALWORLD_ENEMY_DROP_ITEM_RATE", Name: "EnemyDropItemRate", Type: optionFloat, Description: "Item drop rate of defeated enemies"},
+	{Key: "PALWORLD_DEATH_PENALTY", Name: "DeathPenalty", Type: optionEnum, Values: []string{"None", "Item", "ItemAndEquipment", "All"}, Description: "What players drop on death"},
+	{Key: "PALWORLD_ENABLE_PLAYER_TO_PLAYER_DAMAGE", Name: "bEnablePlayerToPlayerDamage", Type: optionBool, Description: "Allow players to damage each other"},
+	{Key: "PALWORLD_ENABLE_FRIENDLY_FIRE", Name: "bEnableFriendlyFire", Type: optionBool, Description: "Allow friendly fire"},
+	{Key: "PALWORLD_ENABLE_INVADER_ENEMY", Name: "bEnableInvaderEnemy", Type: optionBool, Description: "Enable raids on bases"},
+	{Key: "PALWORLD_ACTIVE_UNKO", Name: "bActiveUNKO", Type: optionBool, Description: "Enable UNKO"},
+	{Key: "PALWORLD_ENABLE_AIM_ASSIST_PAD", Name: "bEnableAimAssistPad", Type: optionBool, Description: "Aim assist for controllers"},
+	{Key: "PALWORLD_ENABLE_AIM_ASSIST_KEYBOARD", Name: "bEnableAimAssistKeyboard", Type: optionBool, Description: "Aim assist for keyboards"},
+	{Key: "PALWORLD_DROP_ITEM_MAX_NUM", Name: "DropItemMaxNum", Type: optionInt, Description: "Maximum number of dropped items in the world"},
+	{Key: "PALWORLD_DROP_ITEM_MAX_NUM_UNKO", Name: "DropItemMaxNum_UNKO", Type: optionInt, Description: "Maximum number of dropped UNKO items in the world"},
+	{Key: "PALWORLD_BASE_CAMP_MAX_NUM", Name: "BaseCampMaxNum", Type: optionInt, Description: "Maximum number of bases"},
+	{Key: "PALWORLD_BASE_CAMP_WORKER_MAX_NUM", Name: "BaseCampWorkerMaxNum", Type: optionInt, Description: "Maximum number of Pals working at a base"},
+	{Key: "PALWORLD_DROP_ITEM_ALIVE_MAX_HOURS", Name: "DropItemAliveMaxHours", Type: optionFloat, Description: "Hours until dropped items despawn"},
+	{Key: "PALWORLD_AUTO_RESET_GUILD_NO_ONLINE_PLAYERS", Name: "bAutoResetGuildNoOnlinePlayers", Type: optionBool, Description: "Reset guilds with no online players"},
+	{Key: "PALWORLD_AUTO_RESET_GUILD_TIME_NO_ONLINE_PLAYERS", Name: "AutoResetGuildTimeNoOnlinePlayers", Type: optionFloat, Description: "Hours without online players before a guild is reset"},
+	{Key: "PALWORLD_GUILD_PLAYER_MAX_NUM", Name: "GuildPlayerMaxNum", Type: optionInt, Description: "Maximum number of players in a guild"},
+	{Key: "PALWORLD_BASE_CAMP_MAX_NUM_IN_GUILD", Name: "BaseCampMaxNumInGuild", Type: optionInt, Description: "Maximum number of bases per guild"},
+	{Key: "PALWORLD_PAL_EGG_DEFAULT_HATCHING_TIME", Name: "PalEggDefaultHatchingTime", Type: optionFloat, Description: "Hours to hatch a huge egg"},
+	{Key: "PALWORLD_WORK_SPEED_RATE", Name: "WorkSpeedRate", Type: optionFloat, Description: "Work speed"},
+	{Key: "PALWORLD_AUTO_SAVE_SPAN", Name: "AutoSaveSpan", Type: optionFloat, Description: "Seconds between autosaves"},
+	{Key: "PALWORLD_IS_MULTIPLAY", Name: "bIsMultiplay", Type: optionBool, Description: "Multiplayer"},
+	{Key: "PALWORLD_IS_PVP", Name: "bIsPvP", Type: optionBool, Description: "PvP"},
+	{Key: "PALWORLD_HARDCORE", Name: "bHardcore", Type: optionBool, Description: "Hardcore: dead players can't respawn"},
+	{Key: "PALWORLD_PAL_LOST", Name: "bPalLost", Type: optionBool, Description: "Pals are lost permanently when they die"},
+	{Key: "PALWORLD_CHARACTER_RECREATE_IN_HARDCORE", Name: "bCharacterRecreateInHardcore", Type: optionBool, Description: "Allow recreating a character after dying in hardcore"},
+	{Key: "PALWORLD_CAN_PICKUP_OTHER_GUILD_DEATH_PENALTY_DROP", Name: "bCanPickupOtherGuildDeathPenaltyDrop", Type: optionBool, Description: "Allow picking up other guilds' death drops"},
+	{Key: "PALWORLD_ENABLE_NON_LOGIN_PENALTY", Name: "bEnableNonLoginPenalty", Type: optionBool, Description: "Penalize players who haven't logged in"},
+	{Key: "PALWORLD_ENABLE_FAST_TRAVEL", Name: "bEnableFastTravel", Type: optionBool, Description: "Enable fast travel"},
+	{Key: "PALWORLD_IS_START_LOCATION_SELECT_BY_MAP", Name: "bIsStartLocationSelectByMap", Type: optionBool, Description: "Let players pick their start location on the map"},
+	{Key: "PALWORLD_EXIST_PLAYER_AFTER_LOGOUT", Name: "bExistPlayerAfterLogout", Type: optionBool, Description: "Keep players in the world after they log out"},
+	{Key: "PALWORLD_ENABLE_DEFENSE_OTHER_GUILD_PLAYER", Name: "bEnableDefenseOtherGuildPlayer", Type: optionBool, Description: "Allow defending against other guilds' players"},
+	{Key: "PALWORLD_INVISIBLE_OTHER_GUILD_BASE_CAMP_AREA_FX", Name: "bInvisibleOtherGuildBaseCampAreaFX", Type: optionBool, Description: "Hide other guilds' base area effects"},
+	{Key: "PALWORLD_BUILD_AREA_LIMIT", Name: "bBuildAreaLimit", Type: optionBool, Description: "Forbid building near structures such as fast travel points"},
+	{Key: "PALWORLD_ITEM_WEIGHT_RATE", Name: "ItemWeightRate", Type: optionFloat, Description: "Item weight"},
+	{Key: "PALWORLD_COOP_PLAYER_MAX_NUM", Name: "CoopPlayerMaxNum", Type: optionInt, Description: "Maximum number of players in a co-op session"},
+	{Key: "MAX_PLAYERS", Name: "ServerPlayerMaxNum", Type: optionInt, Description: "Maximum number of players on the server"},
+	{Key: "SERVER_NAME", Name: "ServerName", Type: optionString, Description: "Server name"},
+	{Key: "PALWORLD_SERVER_DESCRIPTION", Name: "ServerDescription", Type: optionString, Description: "Server description"},
+	{Key: "ADMIN_PASSWORD", Name: "AdminPassword", Type: optionString, Description: "Admin password, also used for RCON and the REST API"},
+	{Key: "SERVER_PASSWORD", Name: "ServerPassword", Type: optionString, Description: "Password players need to join"},
+	{Key: "PALWORLD_PUBLIC_PORT", Name: "PublicPort", Type: optionInt, Description: "Port advertised in the community server list"},
+	{Key: "PALWORLD_PUBLIC_IP", Name: "PublicIP", Type: optionString, Description: "IP advertised in the community server list"},
+	{Key: "PALWORLD_RCON_ENABLED", Name: "RCONEnabled", Type: optionBool, Description: "Enable RCON"},
+	{Key: "RCON_PORT", Name: "RCONPort", Type: optionInt, Description: "RCON port"},
+	{Key: "PALWORLD_REGION", Name: "Region", Type: optionString, Description: "Server region"},
+	{Key: "PALWORLD_USE_AUTH", Name: "bUseAuth", Type: optionBool, Description: "Authenticate players"},
+	{Key: "PALWORLD_BAN_LIST_URL", Name: "BanListURL", Type: optionString, Description: "URL of the ban list the server follows"},
+	{Key: "PALWORLD_REST_API_ENABLED", Name: "RESTAPIEnabled", Type: optionBool, Description: "Enable the REST API"},
+	{Key: "REST_API_PORT", Name: "RESTAPIPort", Type: optionInt, Description: "REST API port"},
+	{Key: "PALWORLD_SHOW_PLAYER_LIST", Name: "bShowPlayerList", Type: optionBool, Description: "Show the player list in the ESC menu"},
+	{Key: "PALWORLD_CHAT_POST_LIMIT_PER_MINUTE", Name: "ChatPostLimitPerMinute", Type: optionInt, Description: "Chat messages a player may send per minute"},
+	{Key: "PALWORLD_CROSSPLAY_PLATFORMS", Name: "CrossplayPlatforms", Type: optionList, Values: []string{"Steam", "Xbox", "PS5", "Mac"}, Description: "Platforms allowed to join"},
+	{Key: "PALWORLD_IS_USE_BACKUP_SAVE_DATA", Name: "bIsUseBackupSaveData", Type: optionBool, Description: "Keep world backups"},
+	{Key: "PALWORLD_LOG_FORMAT_TYPE", Name: "LogFormatType", Type: optionEnum, Values: []string{"Text", "Json"}, Description: "Server log format"},
+	{Key: "PALWORLD_SUPPLY_DROP_SPAN", Name: "SupplyDropSpan", Type: optionInt, Description: "Minutes between supply drops"},
+	{Key: "PALWORLD_ENABLE_PREDATOR_BOSS_PAL", Name: "EnablePredatorBossPal", Type: optionBool, Description: "Spawn predator Pals"},
+	{Key: "PALWORLD_MAX_BUILDING_LIMIT_NUM", Name: "MaxBuildingLimitNum", Type: optionInt, Description: "Maximum number of structures per base, 0 for no limit"},
+	{Key: "PALWORLD_SERVER_REPLICATE_PAWN_CULL_DISTANCE", Name: "ServerReplicatePawnCullDistance", Type: optionFloat, Description: "Distance in cm within which Pals are synced to players"},
+	{Key: "PALWORLD_ALLOW_GLOBAL_PALBOX_EXPORT", Name: "bAllowGlobalPalboxExport", Type: optionBool, Description: "Allow exporting Pals to the global Palbox"},
+	{Key: "PALWORLD_ALLOW_GLOBAL_PALBOX_IMPORT", Name: "bAllowGlobalPalboxImport", Type: optionBool, Description: "Allow importing Pals from the global Palbox"},
+	{Key: "PALWORLD_EQUIPMENT_DURABILITY_DAMAGE_RATE", Name: "EquipmentDurabilityDamageRate", Type: optionFloat, Description: "Equipment durability loss rate"},
+	{Key: "PALWORLD_ITEM_CONTAINER_FORCE_MARK_DIRTY_INTERVAL", Name: "ItemContainerForceMarkDirtyInterval", Type: optionFloat, Description: "Seconds between container syncs"},
+}
+
+// encode validates a config value and formats it as the tuple expects:
+// booleans as True/False, floats with six decimals, strings quoted and lists
+// in parentheses
+func (s palworldSetting) encode(value string) (string, error) {
+	switch s.Type {
+	case optionBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("%s: %q is not a boolean", s.Key, value)
+		}
+		if b {
+			return "True", nil
+		}
+		return "False", nil
+	case optionInt:
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return "", fmt.Errorf("%s: %q is not a non-negative integer", s.Key, value)
+		}
+		return strconv.Itoa(n), nil
+	case optionFloat:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || f < 0 {
+			return "", fmt.Errorf("%s: %q is not a non-negative number", s.Key, value)
+		}
+		return strconv.FormatFloat(f, 'f', 6, 64), nil
+	case optionEnum:
+		return s.canonical(value)
+	case optionList:
+		var items []string
+		for _, item := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }) {
+			v, err := s.canonical(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, v)
+		}
+		return "(" + strings.Join(items, ",") + ")", nil
+	}
+	return quoteTupleString(value), nil
+}
+
+// canonical returns the allowed value matching v, ignoring case
+func (s palworldSetting) canonical(v string) (string, error) {
+	for _, allowed := range s.Values {
+		if strings.EqualFold(allowed, v) {
+			return allowed, nil
+		}
+	}
+	return "", fmt.Errorf("%s: %q is not one of %s", s.Key, v, strings.Join(s.Values, ", "))
+}
+
+// quoteTupleString quotes a string value, escaping quotes and backslashes
+func quoteTupleString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// optionTuple is an Unreal struct literal like the OptionSettings value:
+// (Key=Value,...) on one line, where values are numbers, bare words,
+// quoted strings or nested tuples. Values are kept exactly as written, so
+// settings gamekeeper doesn't know survive a rewrite unchanged.
+type optionTuple struct {
+	keys   []string
+	values map[string]string
+}
+
+// parseOptionTuple parses a struct literal, reporting where it is malformed
+func parseOptionTuple(text string) (*optionTuple, error) {
+	t := &optionTuple{values: make(map[string]string)}
+	s := strings.TrimSpace(text)
+	if !strings.HasPrefix(s, "(") || !strings.HasSuffix(s, ")") {
+		return nil, fmt.Errorf("OptionSettings is not enclosed in parentheses")
+	}
+	s = s[1 : len(s)-1]
+
+	for pos := 0; pos < len(s); {
+		eq := strings.IndexAny(s[pos:], "=,()\"")
+		if eq < 0 || s[pos+eq] != '=' {
+			if strings.TrimSpace(s[pos:]) == "" {
+				break
+			}
+			return nil, fmt.Errorf("OptionSettings: expected Key=Value at offset %d", pos+1)
+		}
+		key := strings.TrimSpace(s[pos : pos+eq])
+		if key == "" {
+			return nil, fmt.Errorf("OptionSettings: missing key at offset %d", pos+1)
+		}
+
+		start := pos + eq + 1
+		end, err := scanTupleValue(s, start)
+		if err != nil {
+			return nil, fmt.Errorf("OptionSettings: %s: %w", key, err)
+		}
+		t.Set(key, strings.TrimSpace(s[start:end]))
+
+		pos = end
+		if pos < len(s) {
+			pos++ // the comma
+		}
+	}
+	return t, nil
+}
+
+// scanTupleValue returns where the value starting at start ends: the next
+// comma outside quotes and parentheses, or the end of s
+func scanTupleValue(s string, start int) (int, error) {
+	depth := 0
+	inQuote := false
+	for i := start; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case inQuote && c == '\\':
+			i++
+		case c == '"':
+			inQuote = !inQuote
+		case inQuote:
+		case c == '(':
+			depth++
+		case c == ')':
+			if depth == 0 {
+				return 0, fmt.Errorf("unbalanced ')'")
+			}
+			depth--
+		case c == ',' && depth == 0:
+			return i, nil
+		}
+	}
+	if inQuote {
+		return 0, fmt.Errorf("unterminated string")
+	}
+	if depth > 0 {
+		return 0, fmt.Errorf("unbalanced '('")
+	}
+	return len(s), nil
+}
+
+func (t *optionTuple) Get(key string) (string, bool) {
+	v, ok := t.values[key]
+	return v, ok
+}
+
+// Set replaces a value in place or appends a new key
+func (t *optionTuple) Set(key, value string) {
+	if _, ok := t.values[key]; !ok {
+		t.keys = append(t.keys, key)
+	}
+	t.values[key] = value
+}
+
+func (t *optionTuple) String() string {
+	parts := make([]string, len(t.keys))
+	for i, k := range t.keys {
+		parts[i] = k + "=" + t.values[k]
+	}
+	return "(" + strings.Join(parts, ",") + ")"
+}
+
+// palworldSettingsHeader is the section OptionSettings belongs to
+const palworldSettingsHeader = "[/Script/Pal.PalGameWorldSettings]"
+
+// updateOptionSettings sets values in the OptionSettings line of a
+// PalWorldSettings.ini, keeping every other line and setting. Without an
+// OptionSettings line, the one from seed (DefaultPalWorldSettings.ini) is
+// used. Returns the file and how many settings changed.
+func updateOptionSettings(data, seed []byte, names []string, values map[string]string) ([]byte, int, error) {
+	lines := strings.Split(string(data), "\n")
+	index := optionSettingsLine(lines)
+	if index < 0 {
+		line := "OptionSettings=()"
+		if seedLines := strings.Split(string(seed), "\n"); optionSettingsLine(seedLines) >= 0 {
+			line = strings.TrimSpace(seedLines[optionSettingsLine(seedLines)])
+		}
+
+		header := -1
+		for i, l := range lines {
+			if strings.TrimSpace(l) == palworldSettingsHeader {
+				header = i
+			}
+		}
+		if strings.TrimSpace(string(data)) == "" {
+			lines = []string{palworldSettingsHeader, line, ""}
+			index = 1
+		} else if header < 0 {
+			if lines[len(lines)-1] == "" {
+				lines = lines[:len(lines)-1]
+			}
+			lines = append(lines, palworldSettingsHeader, line, "")
+			index = len(lines) - 2
+		} else {
+			lines = append(lines[:header+1], append([]string{line}, lines[header+1:]...)...)
+			index = header + 1
+		}
+	}
+
+	cr := strings.HasSuffix(lines[index], "\r")
+	current := strings.TrimSuffix(lines[index], "\r")
+	eq := strings.Index(current, "=")
+	tuple, err := parseOptionTuple(current[eq+1:])
+	if err != nil {
+		return nil, 0, err
+	}
+
+	changed := 0
+	for _, name := range names {
+		if old, ok := tuple.Get(name); ok && old == values[name] {
+			continue
+		}
+		tuple.Set(name, values[name])
+		changed++
+	}
+
+	lines[index] = current[:eq+1] + tuple.String()
+	if cr {
+		lines[index] += "\r"
+	}
+	return []byte(strings.Join(lines, "\n")), changed, nil
+}
+
+// optionSettingsLine finds the OptionSettings line, -1 if there is none
+func optionSettingsLine(lines []string) int {
+	for i, l := range lines {
+		if strings.HasPrefix(strings.TrimSpace(l), "OptionSettings=") {
+			return i
+		}
+	}
+	return -1
+}
+
+// palworldSettingValues validates the settings config sets and encodes them
+// for the tuple, in table order
+func (p *PalworldManager) palworldSettingValues() ([]string, map[string]string, error) {
+	var names []string
+	values := make(map[string]string)
+	var errs []error
+	for _, s := range palworldSettings {
+		value, _ := configValue(p.Config.Get(s.Key))
+		if s.Name == "ServerPassword" {
+			password, err := p.serverPassword()
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			value = password
+		}
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+		encoded, err := s.encode(value)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		names = append(names, s.Name)
+		values[s.Name] = encoded
+	}
+	return names, values, errors.Join(errs...)
+}
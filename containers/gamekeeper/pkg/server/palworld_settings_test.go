@@ -0,0 +1,249 @@
+package server
+
+import (
+	"testing"
+)
+
+func TestParseOptionTuple(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want map[string]string
+		out  string
+	}{
+		{
+			name: "numbers and bare words",
+			text: `(Difficulty=None,DayTimeSpeedRate=1.000000,bIsPvP=False)`,
+			want: map[string]string{"Difficulty": "None", "DayTimeSpeedRate": "1.000000", "bIsPvP": "False"},
+			out:  `(Difficulty=None,DayTimeSpeedRate=1.000000,bIsPvP=False)`,
+		},
+		{
+			name: "quoted strings with commas, parentheses and escapes",
+			text: `(ServerName="a, (b)",ServerDescription="say \"hi\", ok",AdminPassword="")`,
+			want: map[string]string{"ServerName": `"a, (b)"`, "ServerDescription": `"say \"hi\", ok"`, "AdminPassword": `""`},
+			out:  `(ServerName="a, (b)",ServerDescription="say \"hi\", ok",AdminPassword="")`,
+		},
+		{
+			name: "nested parentheses",
+			text: `(CrossplayPlatforms=(Steam,Xbox,PS5,Mac),Nested=((A=1,B=(2,3))),Port=8211)`,
+			want: map[string]string{"CrossplayPlatforms": "(Steam,Xbox,PS5,Mac)", "Nested": "((A=1,B=(2,3)))", "Port": "8211"},
+			out:  `(CrossplayPlatforms=(Steam,Xbox,PS5,Mac),Nested=((A=1,B=(2,3))),Port=8211)`,
+		},
+		{
+			name: "negative values kept as written",
+			text: `(PublicPort=-1,CoopPlayerMaxNum=-4,DropItemAliveMaxHours=-0.500000)`,
+			want: map[string]string{"PublicPort": "-1", "CoopPlayerMaxNum": "-4", "DropItemAliveMaxHours": "-0.500000"},
+			out:  `(PublicPort=-1,CoopPlayerMaxNum=-4,DropItemAliveMaxHours=-0.500000)`,
+		},
+		{
+			name: "whitespace trimmed",
+			text: ` ( A = 1 , B = "x" ) `,
+			want: map[string]string{"A": "1", "B": `"x"`},
+			out:  `(A=1,B="x")`,
+		},
+		{
+			name: "empty tuple",
+			text: `()`,
+			want: map[string]string{},
+			out:  `()`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tuple, err := parseOptionTuple(tt.text)
+			if err != nil {
+				t.Fatalf("parseOptionTuple: %v", err)
+			}
+			if len(tuple.keys) != len(tt.want) {
+				t.Errorf("got %d keys, want %d", len(tuple.keys), len(tt.want))
+			}
+			for k, want := range tt.want {
+				if got, ok := tuple.Get(k); !ok || got != want {
+					t.Errorf("%s = %q (present %v), want %q", k, got, ok, want)
+				}
+			}
+			if got := tuple.String(); got != tt.out {
+				t.Errorf("String() = %s, want %s", got, tt.out)
+			}
+		})
+	}
+}
+
+func TestParseOptionTupleErrors(t *testing.T) {
+	tests := map[string]string{
+		"no parentheses":        `Difficulty=None`,
+		"unterminated string":   `(ServerName="abc)`,
+		"unbalanced open":       `(A=(1,2)`,
+		"unbalanced close":      `(A=1),B=2)`,
+		"missing key":           `(=1)`,
+		"missing equals":        `(A=1,B)`,
+		"escaped closing quote": `(A="x\")`,
+	}
+	for name, text := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseOptionTuple(text); err == nil {
+				t.Errorf("parseOptionTuple(%s) succeeded, want an error", text)
+			}
+		})
+	}
+}
+
+func TestOptionTupleSet(t *testing.T) {
+	tuple, err := parseOptionTuple(`(A=1,B=2)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tuple.Set("A", "3")
+	tuple.Set("C", `"new"`)
+	if got, want := tuple.String(), `(A=3,B=2,C="new")`; got != want {
+		t.Errorf("String() = %s, want %s", got, want)
+	}
+}
+
+func TestQuoteTupleString(t *testing.T) {
+	tests := map[string]string{
+		"":         `""`,
+		"plain":    `"plain"`,
+		`say "hi"`: `"say \"hi\""`,
+		`C:\path`:  `"C:\\path"`,
+		"a, (b)":   `"a, (b)"`,
+	}
+	for in, want := range tests {
+		if got := quoteTupleString(in); got != want {
+			t.Errorf("quoteTupleString(%q) = %s, want %s", in, got, want)
+		}
+		tuple, err := parseOptionTuple("(S=" + quoteTupleString(in) + ",N=1)")
+		if err != nil {
+			t.Errorf("quoted %q does not parse back: %v", in, err)
+			continue
+		}
+		if got, _ := tuple.Get("N"); got != "1" {
+			t.Errorf("quoted %q swallowed the next key", in)
+		}
+	}
+}
+
+func TestPalworldSettingEncode(t *testing.T) {
+	boolSetting := palworldSetting{Key: "B", Type: optionBool}
+	intSetting := palworldSetting{Key: "I", Type: optionInt}
+	floatSetting := palworldSetting{Key: "F", Type: optionFloat}
+	stringSetting := palworldSetting{Key: "S", Type: optionString}
+	enumSetting := palworldSetting{Key: "E", Type: optionEnum, Values: []string{"None", "Normal", "Difficult"}}
+	listSetting := palworldSetting{Key: "L", Type: optionList, Values: []string{"Steam", "Xbox", "PS5", "Mac"}}
+
+	tests := []struct {
+		setting palworldSetting
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{setting: boolSetting, value: "true", want: "True"},
+		{setting: boolSetting, value: "0", want: "False"},
+		{setting: boolSetting, value: "yes", wantErr: true},
+		{setting: intSetting, value: "32", want: "32"},
+		{setting: intSetting, value: "-1", wantErr: true},
+		{setting: intSetting, value: "1.5", wantErr: true},
+		{setting: floatSetting, value: "1", want: "1.000000"},
+		{setting: floatSetting, value: "0.5", want: "0.500000"},
+		{setting: floatSetting, value: "2.1234567", want: "2.123457"},
+		{setting: floatSetting, value: "1e2", want: "100.000000"},
+		{setting: floatSetting, value: "-0.5", wantErr: true},
+		{setting: floatSetting, value: "fast", wantErr: true},
+		{setting: stringSetting, value: `My "Pal" Server`, want: `"My \"Pal\" Server"`},
+		{setting: enumSetting, value: "normal", want: "Normal"},
+		{setting: enumSetting, value: "Hard", wantErr: true},
+		{setting: listSetting, value: "steam, xbox,PS5", want: "(Steam,Xbox,PS5)"},
+		{setting: listSetting, value: "Steam,Switch", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.setting.encode(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s encode(%q) = %s, want an error", tt.setting.Key, tt.value, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s encode(%q): %v", tt.setting.Key, tt.value, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s encode(%q) = %s, want %s", tt.setting.Key, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateOptionSettings(t *testing.T) {
+	seed := "; default\n[/Script/Pal.PalGameWorldSettings]\nOptionSettings=(Difficulty=None,ServerName=\"Default Palworld Server\",PublicPort=8211)\n"
+
+	tests := []struct {
+		name    string
+		data    string
+		values  map[string]string
+		want    string
+		changed int
+	}{
+		{
+			name:    "existing line keeps unknown settings and line ending",
+			data:    "[/Script/Pal.PalGameWorldSettings]\r\nOptionSettings=(Difficulty=None,Custom=(1,\"a,b\"),ServerName=\"old\")\r\n",
+			values:  map[string]string{"ServerName": `"new, name"`, "Difficulty": "None"},
+			want:    "[/Script/Pal.PalGameWorldSettings]\r\nOptionSettings=(Difficulty=None,Custom=(1,\"a,b\"),ServerName=\"new, name\")\r\n",
+			changed: 1,
+		},
+		{
+			name:    "empty file seeded from defaults",
+			data:    "",
+			values:  map[string]string{"ServerName": `"Mine"`, "ExpRate": "2.000000"},
+			want:    "[/Script/Pal.PalGameWorldSettings]\nOptionSettings=(Difficulty=None,ServerName=\"Mine\",PublicPort=8211,ExpRate=2.000000)\n",
+			changed: 2,
+		},
+		{
+			name:    "header without OptionSettings",
+			data:    "[/Script/Pal.PalGameWorldSettings]\n; comment\n",
+			values:  map[string]string{"PublicPort": "8211"},
+			want:    "[/Script/Pal.PalGameWorldSettings]\nOptionSettings=(Difficulty=None,ServerName=\"Default Palworld Server\",PublicPort=8211)\n; comment\n",
+			changed: 0,
+		},
+		{
+			name:    "other sections kept, header appended",
+			data:    "[Other]\nKey=Value\n",
+			values:  map[string]string{"Difficulty": "Normal"},
+			want:    "[Other]\nKey=Value\n[/Script/Pal.PalGameWorldSettings]\nOptionSettings=(Difficulty=Normal,ServerName=\"Default Palworld Server\",PublicPort=8211)\n",
+			changed: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var names []string
+			for _, s := range palworldSettings {
+				if _, ok := tt.values[s.Name]; ok {
+					names = append(names, s.Name)
+				}
+			}
+			if len(names) != len(tt.values) {
+				t.Fatalf("test values use names outside palworldSettings")
+			}
+
+			got, changed, err := updateOptionSettings([]byte(tt.data), []byte(seed), names, tt.values)
+			if err != nil {
+				t.Fatalf("updateOptionSettings: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got:\n%q\nwant:\n%q", got, tt.want)
+			}
+			if changed != tt.changed {
+				t.Errorf("changed = %d, want %d", changed, tt.changed)
+			}
+		})
+	}
+}
+
+func TestUpdateOptionSettingsMalformed(t *testing.T) {
+	data := "[/Script/Pal.PalGameWorldSettings]\nOptionSettings=(ServerName=\"broken)\n"
+	if _, _, err := updateOptionSettings([]byte(data), nil, []string{"ServerName"}, map[string]string{"ServerName": `"x"`}); err == nil {
+		t.Error("updateOptionSettings accepted a malformed OptionSettings line")
+	}
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/config"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/gamedef"
+)
+
+// Game describes a supported game and how to create its manager
+type Game struct {
+	Name         string
+	Aliases      []string
+	DisplayName  string
+	Capabilities []gamedef.Capability
+	// Defaults are config values used when config doesn't set them
+	Defaults map[string]string
+	// Definition is the game definition the game runs from, if any
+	Definition *gamedef.Definition
+	// Dedicated is set for games whose manager is written in Go
+	Dedicated bool
+
+	newManager func(*config.Config) Manager
+}
+
+// registered holds the games with a dedicated manager
+var registered []Game
+
+// definitionManagers holds the dedicated managers of games that keep their
+// definition, by game name
+var definitionManagers = make(map[string]func(*DefinitionManager) Manager)
+
+// Register adds a game with a dedicated manager; managers call it from init
+func Register(g Game, newManager func(*config.Config) Manager) {
+	g.newManager = newManager
+	g.Dedicated = true
+	registered = append(registered, g)
+}
+
+// RegisterDefinition gives the game defined under name a dedicated manager
+// built on its definition manager, so the definition (or its replacement in
+// GAME_DEFINITIONS_DIR) still supplies the install source, ports and templates
+func RegisterDefinition(name string, newManager func(*DefinitionManager) Manager) {
+	definitionManagers[name] = newManager
+}
+
+// Games returns the registered games and the games from the definitions
+// (built in, and in dir), sorted by name. A registered game replaces a
+// definition of the same name.
+func Games(dir string) ([]Game, error) {
+	defs, err := gamedef.Load(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]Game)
+	for _, def := range defs {
+		byName[def.Name] = definitionGame(def)
+	}
+	for _, g := range registered {
+		byName[g.Name] = g
+	}
+
+	games := make([]Game, 0, len(byName))
+	for _, g := range byName {
+		games = append(games, g)
+	}
+	sort.Slice(games, func(i, j int) bool { return games[i].Name < games[j].Name })
+	return games, nil
+}
+
+// definitionGame describes a game that runs from its definition. Ports with
+// a config key add their port to the defaults.
+func definitionGame(def *gamedef.Definition) Game {
+	defaults := make(map[string]string)
+	for _, p := range def.Ports {
+		if p.ConfigKey != "" {
+			defaults[p.ConfigKey] = strconv.Itoa(p.Port)
+		}
+	}
+	for k, v := range def.Defaults {
+		defaults[k] = v
+	}
+
+	g := Game{
+		Name:         def.Name,
+		Aliases:      def.Aliases,
+		DisplayName:  def.Title(),
+		Capabilities: def.Capabilities,
+		Defaults:     defaults,
+		Definition:   def,
+		newManager: func(cfg *config.Config) Manager {
+			return NewDefinitionManager(cfg, def)
+		},
+	}
+	if newManager, ok := definitionManagers[def.Name]; ok {
+		g.Dedicated = true
+		g.newManager = func(cfg *config.Config) Manager {
+			return newManager(NewDefinitionManager(cfg, def))
+		}
+	}
+	return g
+}
+
+// LookupGame finds a game by name or alias
+func LookupGame(games []Game, name string) (Game, error) {
+	for _, g := range games {
+		if g.Name == name {
+			return g, nil
+		}
+	}
+	for _, g := range games {
+		for _, alias := range g.Aliases {
+			if alias == name {
+				return g, nil
+			}
+		}
+	}
+
+	names := make([]string, len(games))
+	for i, g := range games {
+		names[i] = g.Name
+	}
+	return Game{}, fmt.Errorf("unknown game %q (supported: %s)", name, strings.Join(names, ", "))
+}
+
+// Has reports whether the game supports a capability
+func (g Game) Has(c gamedef.Capability) bool {
+	for _, have := range g.Capabilities {
+		if have == c {
+			return true
+		}
+	}
+	return false
+}
+
+// NewManager applies the game's defaults to cfg and creates its manager
+func (g Game) NewManager(cfg *config.Config) Manager {
+	for k, v := range g.Defaults {
+		if cfg.Get(k) == nil {
+			cfg.Set(k, v)
+		}
+	}
+	return g.newManager(cfg)
+}
@@ -0,0 +1,253 @@
+package server
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/configfile"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/output"
+)
+
+// sdtdConfigFile is the server config the sdtd definition renders and
+// passes to -configfile
+const sdtdConfigFile = "sdtdconfig.xml"
+
+var (
+	// sdtdPropertyPattern finds property names, commented-out ones included:
+	// the shipped serverconfig.xml documents some options only in comments
+	sdtdPropertyPattern = regexp.MustCompile(`<property\s+name\s*=\s*"([^"]+)"`)
+	modInfoPattern      = regexp.MustCompile(`<(Name|Version)\s+value\s*=\s*"([^"]*)"`)
+)
+
+// SevenDaysManager manages 7 Days to Die servers. The sdtd definition installs
+// and configures the server; the manager adds the admin file, checks the
+// config against the installed build, and handles log files, mods and the
+// UserDataFolder.
+type SevenDaysManager struct {
+	*DefinitionManager
+}
+
+func init() {
+	RegisterDefinition("sdtd", func(d *DefinitionManager) Manager { return NewSevenDaysManager(d) })
+}
+
+func NewSevenDaysManager(d *DefinitionManager) *SevenDaysManager {
+	return &SevenDaysManager{DefinitionManager: d}
+}
+
+func (s *SevenDaysManager) Setup() error {
+	return s.ensureDirectories(s.BaseDir, s.userDataDir(), s.modsDir())
+}
+
+// Configure renders sdtdconfig.xml with UserDataFolder pointing at the user
+// data directory, then writes the lists config owns into serveradmin.xml
+func (s *SevenDaysManager) Configure() error {
+	if err := s.configure(s.adjustServerConfig); err != nil {
+		return err
+	}
+	return s.writeServerAdmin()
+}
+
+func (s *SevenDaysManager) adjustServerConfig(target string, data []byte) ([]byte, error) {
+	if filepath.Base(target) != sdtdConfigFile {
+		return data, nil
+	}
+	return configfile.Set("xml", data, map[string]string{"UserDataFolder": xmlEscape(s.userDataDir())})
+}
+
+// Validate checks the installed build, the command line and the property
+// names in sdtdconfig.xml
+func (s *SevenDaysManager) Validate() error {
+	return errors.Join(s.DefinitionManager.Validate(), s.validateProperties())
+}
+
+// validateProperties checks the properties in sdtdconfig.xml against the
+// serverconfig.xml shipped with the installed build, which names every option
+// that build knows. Unknown properties are a warning, or fail validation
+// when SDTD_STRICT_PROPERTIES is true.
+func (s *SevenDaysManager) validateProperties() error {
+	shipped, err := os.ReadFile(s.serverPath("serverconfig.xml"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	current, err := os.ReadFile(s.serverPath(sdtdConfigFile))
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", sdtdConfigFile, err)
+	}
+	names, err := configfile.Keys("xml", current)
+	if err != nil {
+		return err
+	}
+
+	known := make(map[string]bool)
+	byLower := make(map[string]string)
+	for _, m := range sdtdPropertyPattern.FindAllSubmatch(shipped, -1) {
+		name := string(m[1])
+		known[name] = true
+		byLower[strings.ToLower(name)] = name
+	}
+
+	var unknown []string
+	for _, name := range names {
+		if known[name] {
+			continue
+		}
+		if match, ok := byLower[strings.ToLower(name)]; ok {
+			name += fmt.Sprintf(" (did you mean %s?)", match)
+		}
+		unknown = append(unknown, name)
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	err = fmt.Errorf("%s has properties the installed build doesn't know: %s", sdtdConfigFile, strings.Join(unknown, ", "))
+	if !s.Config.GetBool("SDTD_STRICT_PROPERTIES", false) {
+		output.Warning(err.Error())
+		return nil
+	}
+	return err
+}
+
+// Start launches 7DaysToDieServer.x86_64 with its log going to the console,
+// or to a new file in SDTD_LOG_DIR, which is then watched for readiness
+func (s *SevenDaysManager) Start() error {
+	command, args, err := s.commandLine()
+	if err != nil {
+		return err
+	}
+
+	logFile, err := s.logFile()
+	if err != nil {
+		return err
+	}
+	readyLog := filepath.Join(s.BaseDir, "console.log")
+	if logFile != "" {
+		output.Info(fmt.Sprintf("Logging to %s", logFile))
+		readyLog = logFile
+	} else {
+		logFile = "/dev/stdout"
+	}
+	return s.launch(command, append(args, "-logfile", logFile), readyLog)
+}
+
+// logFile returns a new dated log file in SDTD_LOG_DIR, removing the oldest
+// ones beyond SDTD_LOG_KEEP (default 10), or "" to log to the console
+func (s *SevenDaysManager) logFile() (string, error) {
+	dir := s.Config.GetString("SDTD_LOG_DIR", "")
+	if dir == "" {
+		return "", nil
+	}
+	dir = s.serverPath(dir)
+	if err := ensureDir(dir); err != nil {
+		return "", fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	logs, err := filepath.Glob(filepath.Join(dir, "output_log__*.txt"))
+	if err != nil {
+		return "", err
+	}
+	// Names sort by date; one slot is left for the new log
+	sort.Strings(logs)
+	keep := s.Config.GetInt("SDTD_LOG_KEEP", 10) - 1
+	if keep < 0 {
+		keep = 0
+	}
+	for len(logs) > keep {
+		if err := os.Remove(logs[0]); err != nil {
+			output.Warning(fmt.Sprintf("failed to remove old log: %v", err))
+		}
+		logs = logs[1:]
+	}
+
+	return filepath.Join(dir, fmt.Sprintf("output_log__%s.txt", time.Now().Format("2006-01-02__15-04-05"))), nil
+}
+
+// InstallMods installs workshop items and the mods in mods.yaml into Mods/,
+// then reports the mods the server will load
+func (s *SevenDaysManager) InstallMods() error {
+	if err := s.DefinitionManager.InstallMods(); err != nil {
+		return err
+	}
+
+	if s.Config.Mods.Enabled {
+		for _, mod := range s.Config.Mods.Mods {
+			fmt.Printf("  → Installing mod: %s (%s)\n", mod.Name, mod.Version)
+			if err := s.installConfiguredMod(mod, s.BaseDir); err != nil {
+				return fmt.Errorf("failed to install mod %s: %w", mod.Name, err)
+			}
+		}
+	}
+	return s.checkMods()
+}
+
+// checkMods lists the mods in Mods/ and warns about folders the game will
+// skip because they have no ModInfo.xml, usually a mod unpacked one folder
+// too deep
+func (s *SevenDaysManager) checkMods() error {
+	entries, err := os.ReadDir(s.modsDir())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(s.modsDir(), entry.Name())
+		data, err := os.ReadFile(filepath.Join(dir, "ModInfo.xml"))
+		if err == nil {
+			name, version := entry.Name(), ""
+			for _, m := range modInfoPattern.FindAllSubmatch(data, -1) {
+				if string(m[1]) == "Name" {
+					name = string(m[2])
+				} else {
+					version = string(m[2])
+				}
+			}
+			output.Info(strings.TrimSpace(fmt.Sprintf("Mod %s %s", name, version)))
+			continue
+		}
+
+		nested, _ := filepath.Glob(filepath.Join(dir, "*", "ModInfo.xml"))
+		if len(nested) > 0 {
+			inner, _ := filepath.Rel(s.modsDir(), filepath.Dir(nested[0]))
+			output.Warning(fmt.Sprintf("Mods/%s has no ModInfo.xml and won't load; move Mods/%s up one level", entry.Name(), inner))
+		} else {
+			output.Warning(fmt.Sprintf("Mods/%s has no ModInfo.xml and won't load", entry.Name()))
+		}
+	}
+	return nil
+}
+
+// userDataDir is the UserDataFolder for saves, generated worlds and the admin
+// file: SDTD_USER_DATA_DIR, relative to the server directory, which is the
+// default
+func (s *SevenDaysManager) userDataDir() string {
+	return s.serverPath(s.Config.GetString("SDTD_USER_DATA_DIR", s.BaseDir))
+}
+
+func (s *SevenDaysManager) modsDir() string {
+	return filepath.Join(s.BaseDir, "Mods")
+}
+
+// xmlEscape escapes text for an XML attribute value
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
@@ -0,0 +1,243 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/configfile"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/output"
+)
+
+// serverAdminSkeleton is the admin file written when the game hasn't made one
+const serverAdminSkeleton = `<?xml version="1.0" encoding="UTF-8"?>
+<adminTools>
+	<users>
+	</users>
+	<whitelist>
+	</whitelist>
+	<blacklist>
+	</blacklist>
+	<commands>
+	</commands>
+</adminTools>
+`
+
+var (
+	// sdtdPlayerPattern matches cross-platform player IDs like Steam_76561198000000000
+	sdtdPlayerPattern   = regexp.MustCompile(`^([A-Za-z]+)_([A-Za-z0-9]+)$`)
+	adminCommentPattern = regexp.MustCompile(`(?s)<!--.*?-->`)
+)
+
+// adminSection is a list in serveradmin.xml that config can own. Each entry
+// is a player ID or command, optionally followed by =value.
+type adminSection struct {
+	key     string
+	element string
+	format  func(id, value string) (string, error)
+}
+
+var adminSections = []adminSection{
+	// Admins: Steam_76561198000000000=0, the permission level defaulting to 0
+	{"SDTD_ADMINS", "users", func(id, value string) (string, error) {
+		platform, userID, err := sdtdPlayer(id)
+		if err != nil {
+			return "", err
+		}
+		level, err := permissionLevel(value)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf(`<user platform="%s" userid="%s" permission_level="%d" />`, platform, userID, level), nil
+	}},
+	{"SDTD_WHITELIST", "whitelist", func(id, value string) (string, error) {
+		platform, userID, err := sdtdPlayer(id)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf(`<user platform="%s" userid="%s" />`, platform, userID), nil
+	}},
+	// Bans: Steam_76561198000000000=reason; config bans never expire
+	{"SDTD_BLACKLIST", "blacklist", func(id, value string) (string, error) {
+		platform, userID, err := sdtdPlayer(id)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf(`<blacklisted platform="%s" userid="%s" unbandate="9999-12-31 23:59:59" reason="%s" />`, platform, userID, xmlEscape(value)), nil
+	}},
+	// Command permissions: kick=1
+	{"SDTD_COMMAND_PERMISSIONS", "commands", func(cmd, value string) (string, error) {
+		if value == "" {
+			return "", fmt.Errorf("%q has no permission level", cmd)
+		}
+		level, err := permissionLevel(value)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf(`<permission cmd="%s" permission_level="%d" />`, xmlEscape(cmd), level), nil
+	}},
+}
+
+// writeServerAdmin replaces the sections of serveradmin.xml whose config key
+// is set; the others, and everything the game keeps there, stay as they are
+func (s *SevenDaysManager) writeServerAdmin() error {
+	path := s.serverAdminPath()
+	output.Step(fmt.Sprintf("Writing %s", filepath.Base(path)))
+
+	current, err := os.ReadFile(path)
+	exists := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		output.Error(err.Error())
+		return err
+	}
+	doc := string(current)
+	if !exists {
+		doc = serverAdminSkeleton
+	}
+
+	managed := 0
+	var errs []error
+	for _, section := range adminSections {
+		entries, ok := configList(s.Config, section.key)
+		if !ok {
+			continue
+		}
+		var lines []string
+		for _, entry := range entries {
+			id, value, _ := strings.Cut(entry, "=")
+			line, err := section.format(strings.TrimSpace(id), strings.TrimSpace(value))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", section.key, err))
+				continue
+			}
+			lines = append(lines, line)
+		}
+		if doc, err = setAdminSection(doc, section.element, lines); err != nil {
+			errs = append(errs, err)
+		}
+		managed++
+	}
+	if err := errors.Join(errs...); err != nil {
+		output.Error("invalid admin lists")
+		return err
+	}
+
+	switch {
+	case managed == 0:
+		output.SuccessWithMessage("skipped (no admin lists configured)")
+		return nil
+	case exists && doc == string(current):
+		output.SuccessWithMessage("up to date")
+		return nil
+	}
+	if err := ensureDir(filepath.Dir(path)); err != nil {
+		output.Error(err.Error())
+		return err
+	}
+	if err := os.WriteFile(path, []byte(doc), 0644); err != nil {
+		output.Error(err.Error())
+		return err
+	}
+	output.SuccessWithMessage(fmt.Sprintf("%d list(s) from config", managed))
+	return nil
+}
+
+// serverAdminPath is the AdminFileName from sdtdconfig.xml in the
+// UserDataFolder's Saves directory
+func (s *SevenDaysManager) serverAdminPath() string {
+	name := "serveradmin.xml"
+	if data, err := os.ReadFile(s.serverPath(sdtdConfigFile)); err == nil {
+		if value, ok, _ := configfile.Get("xml", data, "AdminFileName"); ok && value != "" {
+			name = value
+		}
+	}
+	return filepath.Join(s.userDataDir(), "Saves", name)
+}
+
+// setAdminSection replaces the contents of a section of serveradmin.xml,
+// adding the section if the file doesn't have it
+func setAdminSection(doc, element string, lines []string) (string, error) {
+	comments := adminCommentPattern.FindAllStringIndex(doc, -1)
+	inComment := func(pos int) bool {
+		for _, c := range comments {
+			if pos >= c[0] && pos < c[1] {
+				return true
+			}
+		}
+		return false
+	}
+
+	pattern := regexp.MustCompile(fmt.Sprintf(`(?s)<%[1]s\s*/>|<%[1]s\s*>.*?</%[1]s\s*>`, element))
+	start, end := -1, -1
+	for _, m := range pattern.FindAllStringIndex(doc, -1) {
+		if !inComment(m[0]) {
+			start, end = m[0], m[1]
+			break
+		}
+	}
+
+	if start < 0 {
+		closing := strings.LastIndex(doc, "</adminTools>")
+		if closing < 0 {
+			return doc, fmt.Errorf("serveradmin.xml has no <adminTools> element")
+		}
+		// New sections are indented like the first line inside <adminTools>
+		indent := "\t"
+		if open := strings.Index(doc, "<adminTools>"); open >= 0 {
+			rest := strings.TrimLeft(doc[open+len("<adminTools>"):], "\r\n")
+			if first := rest[:len(rest)-len(strings.TrimLeft(rest, " \t"))]; first != "" {
+				indent = first
+			}
+		}
+		lineStart := strings.LastIndex(doc[:closing], "\n") + 1
+		if strings.TrimSpace(doc[lineStart:closing]) != "" {
+			lineStart = closing
+		}
+		return doc[:lineStart] + adminSectionText(element, indent, lines) + "\n" + doc[lineStart:], nil
+	}
+
+	lineStart := strings.LastIndex(doc[:start], "\n") + 1
+	indent := doc[lineStart:start]
+	if strings.TrimSpace(indent) != "" || indent == "" {
+		indent = "\t"
+	}
+	return doc[:start] + strings.TrimPrefix(adminSectionText(element, indent, lines), indent) + doc[end:], nil
+}
+
+// adminSectionText formats a section whose entries are indented one level
+// deeper than indent, the section's own indentation
+func adminSectionText(element, indent string, lines []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s<%s>\n", indent, element)
+	for _, line := range lines {
+		fmt.Fprintf(&b, "%s%s%s\n", indent, indent, line)
+	}
+	fmt.Fprintf(&b, "%s</%s>", indent, element)
+	return b.String()
+}
+
+// sdtdPlayer splits a player ID like Steam_76561198000000000 into its
+// platform and user ID
+func sdtdPlayer(id string) (string, string, error) {
+	m := sdtdPlayerPattern.FindStringSubmatch(id)
+	if m == nil {
+		return "", "", fmt.Errorf("invalid player ID %q (want <platform>_<id>, e.g. Steam_76561198000000000)", id)
+	}
+	return m[1], m[2], nil
+}
+
+// permissionLevel parses a permission level, 0 (full access) to 1000
+func permissionLevel(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	level, err := strconv.Atoi(value)
+	if err != nil || level < 0 || level > 1000 {
+		return 0, fmt.Errorf("invalid permission level %q (want 0 to 1000)", value)
+	}
+	return level, nil
+}
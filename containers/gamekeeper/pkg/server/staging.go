@@ -0,0 +1,339 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/fsutil"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/output"
+)
+
+// stateDirName is gamekeeper's private directory inside DataDir
+const stateDirName = ".gamekeeper"
+
+// stagedInstall installs a new build next to the live one and swaps it in
+// once it has been validated, so a failed update never touches the live build.
+//
+// The swap renames each staged top-level entry into place after moving the
+// live entry aside. Preserved paths (world/save data) found inside a replaced
+// entry are moved back into the new build, so they are never copied or lost.
+// Every rename is recorded in a journal before it is made, so a swap cut
+// short by a crash is rolled back on the next start.
+type stagedInstall struct {
+	liveDir    string
+	stagingDir string
+	// previousDir receives the live entries displaced by the swap
+	previousDir string
+	// journal lists the renames of a swap in progress
+	journal string
+	// entries limits the swap to these top-level names; empty means everything staged
+	entries []string
+	// preserve lists paths relative to liveDir that are never replaced
+	preserve []string
+}
+
+// stagedInstall returns a staged install for liveDir using gamekeeper's state directory
+func (b *BaseManager) stagedInstall(liveDir string, entries, preserve []string) *stagedInstall {
+	stateDir := filepath.Join(b.DataDir, stateDirName)
+	return &stagedInstall{
+		liveDir:     liveDir,
+		stagingDir:  filepath.Join(stateDir, "staging"),
+		previousDir: filepath.Join(stateDir, "previous"),
+		journal:     filepath.Join(stateDir, "swap.json"),
+		entries:     entries,
+		preserve:    preserve,
+	}
+}
+
+// stagedUpdatesEnabled reports whether updates should go through a staging directory
+func (b *BaseManager) stagedUpdatesEnabled() bool {
+	return b.Config.GetBool("STAGED_UPDATES", true)
+}
+
+// Prepare creates an empty staging directory. When seed is true the current
+// live installation is copied in first, so tools like steamcmd only have to
+// download what changed.
+func (s *stagedInstall) Prepare(seed bool) error {
+	// The staging directory may hold half of an interrupted swap
+	if _, err := s.Recover(); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(s.stagingDir); err != nil {
+		return fmt.Errorf("failed to clear staging directory: %w", err)
+	}
+	if err := ensureDir(s.stagingDir); err != nil {
+		return err
+	}
+	if !seed {
+		return nil
+	}
+
+	entries, err := os.ReadDir(s.liveDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if !s.swaps(name) || isStateEntry(name) {
+			continue
+		}
+		src := filepath.Join(s.liveDir, name)
+		dst := filepath.Join(s.stagingDir, name)
+		if err := s.copyExcludingPreserved(src, dst, name); err != nil {
+			return fmt.Errorf("failed to seed staging directory: %w", err)
+		}
+	}
+	return nil
+}
+
+// Swap moves the staged build into place. On failure every rename done so
+// far is reverted and the live installation is left as it was.
+func (s *stagedInstall) Swap() error {
+	if err := os.RemoveAll(s.previousDir); err != nil {
+		return fmt.Errorf("failed to clear previous build directory: %w", err)
+	}
+	if err := ensureDir(s.previousDir); err != nil {
+		return err
+	}
+
+	staged, err := os.ReadDir(s.stagingDir)
+	if err != nil {
+		return fmt.Errorf("failed to read staging directory: %w", err)
+	}
+
+	// Each rename is journaled before it is made so it can be undone in
+	// reverse order, now or by Recover after a crash
+	var done []swapRename
+	move := func(from, to string) error {
+		if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+			return err
+		}
+		if err := s.writeJournal(append(done, swapRename{from, to})); err != nil {
+			return err
+		}
+		if err := os.Rename(from, to); err != nil {
+			return err
+		}
+		done = append(done, swapRename{from, to})
+		return nil
+	}
+	revert := func() {
+		undoRenames(done)
+		os.Remove(s.journal)
+	}
+
+	for _, entry := range staged {
+		name := entry.Name()
+		if !s.swaps(name) || s.isPreserved(name) || isStateEntry(name) {
+			continue
+		}
+
+		live := filepath.Join(s.liveDir, name)
+		if fileExists(live) {
+			if err := move(live, filepath.Join(s.previousDir, name)); err != nil {
+				revert()
+				return fmt.Errorf("failed to move %s aside: %w", name, err)
+			}
+		}
+		if err := move(filepath.Join(s.stagingDir, name), live); err != nil {
+			revert()
+			return fmt.Errorf("failed to move staged %s into place: %w", name, err)
+		}
+
+		// Carry preserved data from the old entry over into the new one
+		for _, p := range s.preserve {
+			if !strings.HasPrefix(p, name+"/") {
+				continue
+			}
+			old := filepath.Join(s.previousDir, p)
+			if !fileExists(old) {
+				continue
+			}
+			target := filepath.Join(s.liveDir, p)
+			if fileExists(target) {
+				// The build shipped its own default; park it in the staging dir
+				if err := move(target, filepath.Join(s.stagingDir, p)); err != nil {
+					revert()
+					return fmt.Errorf("failed to replace %s: %w", p, err)
+				}
+			}
+			if err := move(old, target); err != nil {
+				revert()
+				return fmt.Errorf("failed to preserve %s: %w", p, err)
+			}
+		}
+	}
+
+	// Removing the journal commits the swap
+	if err := os.Remove(s.journal); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.RemoveAll(s.stagingDir)
+}
+
+// swapRename is one rename of a swap, as recorded in the journal
+type swapRename struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Recover rolls back a swap that was cut short, restoring the live build it
+// started from. It reports whether there was one.
+func (s *stagedInstall) Recover() (bool, error) {
+	data, err := os.ReadFile(s.journal)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	var done []swapRename
+	if err := json.Unmarshal(data, &done); err != nil {
+		return false, fmt.Errorf("corrupt swap journal %s: %w", s.journal, err)
+	}
+	if err := undoRenames(done); err != nil {
+		return true, fmt.Errorf("failed to roll back interrupted update: %w", err)
+	}
+	if err := os.RemoveAll(s.stagingDir); err != nil {
+		return true, err
+	}
+	return true, os.Remove(s.journal)
+}
+
+// writeJournal replaces the journal, through a temporary file so a crash
+// never leaves a torn one
+func (s *stagedInstall) writeJournal(done []swapRename) error {
+	data, err := json.Marshal(done)
+	if err != nil {
+		return err
+	}
+	tmp := s.journal + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write swap journal: %w", err)
+	}
+	return os.Rename(tmp, s.journal)
+}
+
+// undoRenames reverts renames in reverse order. A journaled rename that was
+// never made, or was already undone, is skipped.
+func undoRenames(done []swapRename) error {
+	for i := len(done) - 1; i >= 0; i-- {
+		r := done[i]
+		if !fileExists(r.To) || fileExists(r.From) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(r.From), 0755); err != nil {
+			return err
+		}
+		if err := os.Rename(r.To, r.From); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recoverSwap rolls back an update whose swap was cut short
+func (b *BaseManager) recoverSwap() error {
+	recovered, err := b.stagedInstall(b.BaseDir, nil, nil).Recover()
+	if !recovered {
+		return err
+	}
+	output.Step("Rolling back interrupted update")
+	if err != nil {
+		output.Error(err.Error())
+		return err
+	}
+	output.Success()
+	return nil
+}
+
+// RecoverUpdate rolls back an update that was interrupted while its build
+// was being swapped in, so the server never starts from a half-swapped tree
+func RecoverUpdate(m Manager) error {
+	if r, ok := m.(interface{ recoverSwap() error }); ok {
+		return r.recoverSwap()
+	}
+	return nil
+}
+
+// Discard removes the staging directory after a failed update
+func (s *stagedInstall) Discard() {
+	os.RemoveAll(s.stagingDir)
+}
+
+// swaps reports whether a top-level entry takes part in the swap
+func (s *stagedInstall) swaps(name string) bool {
+	if len(s.entries) == 0 {
+		return true
+	}
+	for _, e := range s.entries {
+		if e == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isPreserved reports whether rel (relative to liveDir) is preserved data
+func (s *stagedInstall) isPreserved(rel string) bool {
+	for _, p := range s.preserve {
+		if rel == p {
+			return true
+		}
+	}
+	return false
+}
+
+// copyExcludingPreserved copies src to dst, skipping preserved paths below it
+func (s *stagedInstall) copyExcludingPreserved(src, dst, rel string) error {
+	if s.isPreserved(rel) {
+		return nil
+	}
+
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		link, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(link, dst)
+	}
+	if !info.IsDir() {
+		return fsutil.CopyFile(src, dst)
+	}
+
+	if err := os.MkdirAll(dst, info.Mode().Perm()); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if err := s.copyExcludingPreserved(filepath.Join(src, name), filepath.Join(dst, name), rel+"/"+name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isStateEntry reports whether a top-level entry holds gamekeeper state or
+// runtime output rather than game files
+func isStateEntry(name string) bool {
+	switch name {
+	case stateDirName, ".steam-workshop", ".hytale-curseforge-mods", "console.log":
+		return true
+	}
+	return false
+}
@@ -5,95 +5,129 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 
-	"github.com/kubelize/game-servers/gamekeeper/pkg/config"
-	"github.com/kubelize/game-servers/gamekeeper/pkg/rcon"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/output"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/workshop"
 )
 
-// SteamManager manages Steam-based game servers
-type SteamManager struct {
-	*BaseManager
-	appID int
-}
+// steamUpdate installs or updates the server with steamcmd
+func (d *DefinitionManager) steamUpdate() error {
+	steam := d.def.Install.Steam
+	fmt.Printf("  → Installing/updating %s (Steam AppID: %d)...\n", d.GameType, steam.AppID)
 
-// NewSteamManager creates a new Steam game server manager
-func NewSteamManager(cfg *config.Config, gameType string, appID int) *SteamManager {
-	baseDir := cfg.GetString("BASE_DIR", "/home/kubelize/server")
-	
-	return &SteamManager{
-		BaseManager: &BaseManager{
-			GameType: gameType,
-			Config:   cfg,
-			BaseDir:  baseDir,
-			DataDir:  baseDir,
-		},
-		appID: appID,
+	if d.pinnedSkip() {
+		return nil
 	}
-}
 
-func (s *SteamManager) Setup() error {
-	return s.ensureDirectories(s.BaseDir)
+	if !d.stagedUpdatesEnabled() {
+		if err := d.steamInstall(d.BaseDir); err != nil {
+			return err
+		}
+		return d.buildHistory().SetCurrent(steamBuildID(d.BaseDir, steam.AppID))
+	}
+
+	// Install into a copy of the live build so a failed run never touches it
+	stage := d.stagedInstall(d.BaseDir, nil, d.preservePaths())
+
+	output.Step("Preparing staging directory")
+	if err := stage.Prepare(true); err != nil {
+		output.Error(err.Error())
+		stage.Discard()
+		return err
+	}
+	output.Success()
+
+	if err := d.steamInstall(stage.stagingDir); err != nil {
+		stage.Discard()
+		return fmt.Errorf("steamcmd failed: %w", err)
+	}
+
+	output.Step("Validating staged build")
+	if err := d.staged(stage.stagingDir).validateBuild(); err != nil {
+		output.Error(err.Error())
+		stage.Discard()
+		return fmt.Errorf("staged build is invalid: %w", err)
+	}
+	output.Success()
+
+	if err := d.commitStaged(stage, steamBuildID(stage.stagingDir, steam.AppID), d.worldPaths()); err != nil {
+		stage.Discard()
+		return err
+	}
+	return nil
 }
 
-func (s *SteamManager) Update(force bool) error {
-	fmt.Printf("  → Installing/updating %s (Steam AppID: %d)...\n", s.GameType, s.appID)
-	
-	steamCmd := "/home/kubelize/steam/steamcmd.sh"
+// steamInstall runs steamcmd app_update into dir. STEAM_BRANCH overrides the
+// definition's beta branch.
+func (d *DefinitionManager) steamInstall(dir string) error {
+	steam := d.def.Install.Steam
 	args := []string{
-		"+force_install_dir", s.BaseDir,
+		"+force_install_dir", dir,
 		"+login", "anonymous",
-		"+app_update", fmt.Sprintf("%d", s.appID),
-		"validate",
-		"+quit",
+		"+app_update", fmt.Sprintf("%d", steam.AppID),
+	}
+	if branch := d.Config.GetString("STEAM_BRANCH", steam.Branch); branch != "" {
+		args = append(args, "-beta", branch)
 	}
+	args = append(args, "validate", "+quit")
 
-	cmd := exec.Command(steamCmd, args...)
+	cmd := exec.Command(d.steamCmdPath(), args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
-	
+
 	return cmd.Run()
 }
 
-func (s *SteamManager) CheckUpdate() (bool, string, error) {
-	// SteamCMD handles updates automatically
-	return false, "", nil
+// steamBuildID reads the installed build ID from the app manifest steamcmd writes
+func steamBuildID(dir string, appID int) string {
+	data, err := os.ReadFile(steamManifestPath(dir, appID))
+	if err != nil {
+		return ""
+	}
+	if m := regexp.MustCompile(`"buildid"\s+"(\d+)"`).FindSubmatch(data); m != nil {
+		return string(m[1])
+	}
+	return ""
 }
 
-func (s *SteamManager) InstallMods() error {
-	// TODO: Implement Steam Workshop mod installation
-	fmt.Println("  ℹ  Steam mod management not yet implemented")
-	return nil
+// steamManifestPath is the app manifest, which steamcmd only writes once an
+// install has completed
+func steamManifestPath(dir string, appID int) string {
+	return filepath.Join(dir, "steamapps", fmt.Sprintf("appmanifest_%d.acf", appID))
 }
 
-func (s *SteamManager) Configure() error {
-	// Game-specific configuration handled by subclasses or config files
-	return nil
-}
+// InstallMods installs Steam Workshop items for games that support them
+func (d *DefinitionManager) InstallMods() error {
+	items := d.Config.GetString("STEAM_WORKSHOP_ITEMS", "")
+	collections := d.Config.GetString("STEAM_WORKSHOP_COLLECTIONS", "")
 
-func (s *SteamManager) Validate() error {
-	// Check if server files exist
-	if !fileExists(s.BaseDir) {
-		return fmt.Errorf("server directory does not exist: %s", s.BaseDir)
+	defaultAppID := 0
+	if steam := d.def.Install.Steam; steam != nil {
+		defaultAppID = steam.WorkshopAppID
+	}
+	appID := d.Config.GetInt("STEAM_WORKSHOP_APP_ID", defaultAppID)
+	if appID == 0 {
+		if items != "" || collections != "" {
+			output.Warning(fmt.Sprintf("Steam Workshop is not supported for %s", d.GameType))
+		} else {
+			output.Info("No mods configured")
+		}
+		return nil
 	}
-	return nil
-}
 
-func (s *SteamManager) Start() error {
-	consolePort := s.Config.GetString("CONSOLE_PORT", "8080")
-	sessionName := s.Config.GetString("TMUX_SESSION_NAME", fmt.Sprintf("%s-server", s.GameType))
-	
-	// Get the start command and args from config
-	startCommand := s.Config.GetString("START_COMMAND", filepath.Join(s.BaseDir, "startserver.sh"))
-	startArgs := s.Config.GetString("START_ARGS", "")
-	
-	var args []string
-	if startArgs != "" {
-		args = splitArgs(startArgs)
+	wsManager, err := workshop.NewManager(d.Config, appID, d.steamCmdPath(), d.DataDir)
+	if err != nil {
+		return fmt.Errorf("workshop setup failed: %w", err)
 	}
-	
-	return rcon.StartServerWithTmux(consolePort, sessionName, startCommand, args, s.BaseDir)
-}
 
-func (s *SteamManager) Stop() error {
+	if err := wsManager.InstallMods(items, collections, d.workshopPlacer()); err != nil {
+		return fmt.Errorf("workshop mod installation failed: %w", err)
+	}
 	return nil
 }
+
+// steamCmdPath returns the location of the steamcmd launcher script
+func (d *DefinitionManager) steamCmdPath() string {
+	return d.Config.GetString("STEAMCMD_PATH", "/home/kubelize/steam/steamcmd.sh")
+}
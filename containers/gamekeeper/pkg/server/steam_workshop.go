@@ -0,0 +1,140 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/fsutil"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/workshop"
+)
+
+// workshopPlacer returns the placer that puts workshop content where the game expects it
+func (d *DefinitionManager) workshopPlacer() workshop.Placer {
+	switch d.GameType {
+	case "conan-exiles":
+		return &conanModPlacer{modsDir: filepath.Join(d.BaseDir, "ConanSandbox", "Mods")}
+	case "sdtd":
+		return &sevenDaysModPlacer{modsDir: filepath.Join(d.BaseDir, "Mods")}
+	default:
+		return &dirModPlacer{modsDir: filepath.Join(d.BaseDir, "Mods")}
+	}
+}
+
+// conanModPlacer copies .pak files into ConanSandbox/Mods and writes modlist.txt
+type conanModPlacer struct {
+	modsDir string
+}
+
+func (p *conanModPlacer) Place(item workshop.Item) ([]string, error) {
+	paks, err := filepath.Glob(filepath.Join(item.ContentDir, "*.pak"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paks) == 0 {
+		return nil, fmt.Errorf("no .pak files found in workshop item %s", item.ID)
+	}
+
+	if err := ensureDir(p.modsDir); err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, pak := range paks {
+		dest := filepath.Join(p.modsDir, filepath.Base(pak))
+		if err := fsutil.CopyFile(pak, dest); err != nil {
+			return paths, err
+		}
+		paths = append(paths, dest)
+	}
+	return paths, nil
+}
+
+func (p *conanModPlacer) Finalize(items []workshop.Item) error {
+	if err := ensureDir(p.modsDir); err != nil {
+		return err
+	}
+
+	// modlist.txt defines the load order; entries prefixed with * are relative to the Mods folder
+	var lines []string
+	for _, item := range items {
+		paks, err := filepath.Glob(filepath.Join(item.ContentDir, "*.pak"))
+		if err != nil {
+			return err
+		}
+		for _, pak := range paks {
+			lines = append(lines, "*"+filepath.Base(pak))
+		}
+	}
+
+	content := strings.Join(lines, "\n")
+	if len(lines) > 0 {
+		content += "\n"
+	}
+	return os.WriteFile(filepath.Join(p.modsDir, "modlist.txt"), []byte(content), 0644)
+}
+
+// sevenDaysModPlacer copies mod folders (identified by ModInfo.xml) into Mods/
+type sevenDaysModPlacer struct {
+	modsDir string
+}
+
+func (p *sevenDaysModPlacer) Place(item workshop.Item) ([]string, error) {
+	if err := ensureDir(p.modsDir); err != nil {
+		return nil, err
+	}
+
+	// An item is either a single mod folder or a bundle of mod folders
+	if fileExists(filepath.Join(item.ContentDir, "ModInfo.xml")) {
+		dest := filepath.Join(p.modsDir, item.ID)
+		if err := copyDir(item.ContentDir, dest); err != nil {
+			return nil, err
+		}
+		return []string{dest}, nil
+	}
+
+	entries, err := os.ReadDir(item.ContentDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		src := filepath.Join(item.ContentDir, entry.Name())
+		if !entry.IsDir() || !fileExists(filepath.Join(src, "ModInfo.xml")) {
+			continue
+		}
+		dest := filepath.Join(p.modsDir, entry.Name())
+		if err := copyDir(src, dest); err != nil {
+			return paths, err
+		}
+		paths = append(paths, dest)
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no ModInfo.xml found in workshop item %s", item.ID)
+	}
+	return paths, nil
+}
+
+func (p *sevenDaysModPlacer) Finalize(items []workshop.Item) error {
+	return nil
+}
+
+// dirModPlacer copies each item into its own folder under Mods/
+type dirModPlacer struct {
+	modsDir string
+}
+
+func (p *dirModPlacer) Place(item workshop.Item) ([]string, error) {
+	dest := filepath.Join(p.modsDir, item.ID)
+	if err := copyDir(item.ContentDir, dest); err != nil {
+		return nil, err
+	}
+	return []string{dest}, nil
+}
+
+func (p *dirModPlacer) Finalize(items []workshop.Item) error {
+	return nil
+}
@@ -2,11 +2,15 @@ package server
 
 import (
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/config"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/download"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/fsutil"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/output"
 )
 
 // ensureDir creates a directory if it doesn't exist
@@ -20,34 +24,39 @@ func fileExists(path string) bool {
 	return err == nil
 }
 
-// downloadFile downloads a file from a URL
-func downloadFile(url, dest string) error {
-	// Create destination directory
-	if err := ensureDir(filepath.Dir(dest)); err != nil {
-		return err
-	}
-
-	// Create the file
-	out, err := os.Create(dest)
+// download fetches url to dest with retries and resume, verifying checksum
+// ("algo:hex") when given, and prints progress on the current step line.
+// cacheKey names the artifact in the shared cache when it has no checksum.
+func (b *BaseManager) download(url, dest, checksum, cacheKey string) error {
+	sums, err := download.ParseChecksum(checksum)
 	if err != nil {
 		return err
 	}
-	defer out.Close()
+	return download.New(b.Config).Get(url, dest, download.Options{
+		Checksums: sums,
+		Progress:  output.Progress(),
+		CacheKey:  cacheKey,
+	})
+}
 
-	// Download
-	resp, err := http.Get(url)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
+// copyDir recursively copies a directory tree
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("bad status: %s", resp.Status)
-	}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
 
-	// Write to file
-	_, err = io.Copy(out, resp.Body)
-	return err
+		if info.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm())
+		}
+		return fsutil.CopyFile(path, target)
+	})
 }
 
 // splitArgs splits a string into arguments (respects quotes)
@@ -82,3 +91,62 @@ func splitArgs(s string) []string {
 	
 	return args
 }
+
+// configValue formats a raw config value; YAML numbers and booleans are
+// accepted as well as strings
+func configValue(v interface{}) (string, bool) {
+	switch val := v.(type) {
+	case nil:
+		return "", false
+	case string:
+		return val, true
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64), true
+	default:
+		return fmt.Sprint(val), true
+	}
+}
+
+// configList reads a configured list: a YAML list, or a string of entries
+// separated by commas or newlines. ok is false when the key isn't set.
+func configList(cfg *config.Config, key string) ([]string, bool) {
+	raw := cfg.Get(key)
+	if raw == nil {
+		return nil, false
+	}
+
+	var items []string
+	switch v := raw.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := configValue(item); ok {
+				items = append(items, s)
+			}
+		}
+	default:
+		s, _ := configValue(v)
+		items = strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '\n' })
+	}
+
+	var entries []string
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			entries = append(entries, item)
+		}
+	}
+	return entries, true
+}
+
+// configFlag reads a boolean setting; YAML booleans, 1/0 and true/false are
+// accepted
+func configFlag(cfg *config.Config, key string, def bool) (bool, error) {
+	value, ok := configValue(cfg.Get(key))
+	if !ok || value == "" {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return def, fmt.Errorf("%s: invalid boolean %q", key, value)
+	}
+	return b, nil
+}
@@ -0,0 +1,205 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/output"
+)
+
+// valheimPlayerPattern matches the IDs in Valheim's player lists: a SteamID64
+// or a platform ID like Steam_76561198000000000 or Xbox_2535400000000000
+var valheimPlayerPattern = regexp.MustCompile(`^(\d+|[A-Za-z]+_[A-Za-z0-9-]+)$`)
+
+// valheimList is a player list file in the save directory and the config key
+// that owns it
+type valheimList struct {
+	key    string
+	file   string
+	header string
+}
+
+var valheimLists = []valheimList{
+	{"VALHEIM_ADMINS", "adminlist.txt", "// List admin players ID  ONE per line"},
+	{"VALHEIM_BANNED", "bannedlist.txt", "// List banned players ID  ONE per line"},
+	{"VALHEIM_PERMITTED", "permittedlist.txt", "// List permitted players ID ONE per line"},
+}
+
+// ValheimManager manages Valheim servers. The valheim definition installs the
+// server; the manager builds its arguments from config, checks the password
+// and keeps the player lists and worlds in the save directory.
+type ValheimManager struct {
+	*DefinitionManager
+}
+
+func init() {
+	RegisterDefinition("valheim", func(d *DefinitionManager) Manager { return NewValheimManager(d) })
+}
+
+func NewValheimManager(d *DefinitionManager) *ValheimManager {
+	return &ValheimManager{DefinitionManager: d}
+}
+
+func (v *ValheimManager) Setup() error {
+	return v.ensureDirectories(v.BaseDir, v.saveDir())
+}
+
+// Configure renders the definition's templates and writes the player lists
+// config owns
+func (v *ValheimManager) Configure() error {
+	if err := v.DefinitionManager.Configure(); err != nil {
+		return err
+	}
+	return v.writePlayerLists()
+}
+
+// Validate checks the installed build, the command line and the server
+// arguments, and warns when worlds would be saved off the server volume
+func (v *ValheimManager) Validate() error {
+	if rel, err := filepath.Rel(v.DataDir, v.saveDir()); err != nil || strings.HasPrefix(rel, "..") {
+		output.Warning(fmt.Sprintf("VALHEIM_SAVE_DIR %s is outside %s; worlds there are lost with the pod unless it is a volume", v.saveDir(), v.DataDir))
+	}
+	_, err := v.serverArgs()
+	return errors.Join(v.DefinitionManager.Validate(), err)
+}
+
+func (v *ValheimManager) Start() error {
+	command, args, err := v.commandLine()
+	if err != nil {
+		return err
+	}
+	serverArgs, err := v.serverArgs()
+	if err != nil {
+		return err
+	}
+	return v.launch(command, append(args, serverArgs...), filepath.Join(v.BaseDir, "console.log"))
+}
+
+// serverArgs builds valheim_server.x86_64's arguments from config and checks
+// them against Valheim's rules: a password has at least 5 characters and
+// isn't part of the server name, and only private servers may go without one
+func (v *ValheimManager) serverArgs() ([]string, error) {
+	name := v.Config.GetString("SERVER_NAME", "Valheim Server")
+	world := v.Config.GetString("WORLD_NAME", "Dedicated")
+
+	var errs []error
+	public, err := configFlag(v.Config, "SERVER_PUBLIC", false)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	crossplay, err := configFlag(v.Config, "VALHEIM_CROSSPLAY", false)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	password, err := v.serverPassword()
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	switch {
+	case strings.TrimSpace(name) == "":
+		errs = append(errs, fmt.Errorf("SERVER_NAME must not be empty"))
+	case strings.TrimSpace(world) == "":
+		errs = append(errs, fmt.Errorf("WORLD_NAME must not be empty"))
+	}
+	switch {
+	case password == "" && public:
+		errs = append(errs, fmt.Errorf("a public server needs a password (SERVER_PASSWORD)"))
+	case password != "" && len([]rune(password)) < 5:
+		errs = append(errs, fmt.Errorf("the server password must be at least 5 characters"))
+	case password != "" && strings.Contains(name, password):
+		errs = append(errs, fmt.Errorf("the server password must not be part of the server name"))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("invalid server settings:\n%w", err)
+	}
+
+	port := 2456
+	for _, p := range v.Ports() {
+		if p.Name == "game" {
+			port = p.Port
+		}
+	}
+
+	publicArg := "0"
+	if public {
+		publicArg = "1"
+	}
+	args := []string{
+		"-name", name,
+		"-port", strconv.Itoa(port),
+		"-world", world,
+		"-public", publicArg,
+		"-savedir", v.saveDir(),
+	}
+	if password != "" {
+		// Valheim has no other way to take it, so the password shows up in
+		// ps output and the tmux pane's history
+		args = append(args, "-password", password)
+	}
+	if crossplay {
+		args = append(args, "-crossplay")
+	}
+	return args, nil
+}
+
+// writePlayerLists writes adminlist.txt, bannedlist.txt and permittedlist.txt
+// for the keys set in config. A list config owns is replaced on every start,
+// keeping the file's comments; the others are left to the game.
+func (v *ValheimManager) writePlayerLists() error {
+	for _, list := range valheimLists {
+		ids, ok := configList(v.Config, list.key)
+		if !ok {
+			continue
+		}
+
+		output.Step(fmt.Sprintf("Writing %s", list.file))
+		for _, id := range ids {
+			if !valheimPlayerPattern.MatchString(id) {
+				err := fmt.Errorf("%s: invalid player ID %q (want a SteamID64 or <platform>_<id>)", list.key, id)
+				output.Error(err.Error())
+				return err
+			}
+		}
+
+		path := filepath.Join(v.saveDir(), list.file)
+		var lines []string
+		if data, err := os.ReadFile(path); err == nil {
+			for _, line := range strings.Split(string(data), "\n") {
+				if strings.HasPrefix(strings.TrimSpace(line), "//") {
+					lines = append(lines, strings.TrimRight(line, "\r"))
+				}
+			}
+		}
+		if len(lines) == 0 {
+			lines = []string{list.header}
+		}
+		content := strings.Join(append(lines, ids...), "\n") + "\n"
+
+		if current, err := os.ReadFile(path); err == nil && string(current) == content {
+			output.SuccessWithMessage("up to date")
+			continue
+		}
+		if err := ensureDir(v.saveDir()); err != nil {
+			output.Error(err.Error())
+			return err
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			output.Error(err.Error())
+			return err
+		}
+		output.SuccessWithMessage(fmt.Sprintf("%d player(s)", len(ids)))
+	}
+	return nil
+}
+
+// saveDir is where worlds and player lists are kept: VALHEIM_SAVE_DIR,
+// relative to the server directory, which defaults to saves on the PVC
+func (v *ValheimManager) saveDir() string {
+	return v.serverPath(v.Config.GetString("VALHEIM_SAVE_DIR", "saves"))
+}
@@ -0,0 +1,477 @@
+package workshop
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kubelize/game-servers/gamekeeper/pkg/config"
+	"github.com/kubelize/game-servers/gamekeeper/pkg/output"
+)
+
+const steamAPIBase = "https://api.steampowered.com"
+
+// Client handles Steam Web API interactions for workshop metadata
+type Client struct {
+	httpClient *http.Client
+	apiBase    string
+}
+
+// FileDetails represents a published workshop file
+type FileDetails struct {
+	PublishedFileID string `json:"publishedfileid"`
+	Result          int    `json:"result"`
+	Title           string `json:"title"`
+	ConsumerAppID   int    `json:"consumer_app_id"`
+	TimeUpdated     int64  `json:"time_updated"`
+}
+
+// Item is a workshop item resolved for installation, in load order
+type Item struct {
+	ID          string
+	Title       string
+	TimeUpdated int64
+	ContentDir  string
+}
+
+// Placer copies downloaded workshop content into the game's mod location
+type Placer interface {
+	// Place installs a single item and returns the paths it created
+	Place(item Item) ([]string, error)
+
+	// Finalize runs once all items are placed (e.g. to write a load order file)
+	Finalize(items []Item) error
+}
+
+// Manifest tracks installed workshop items
+type Manifest struct {
+	SchemaVersion  int                     `json:"schemaVersion"`
+	AppID          int                     `json:"appId"`
+	LastCheckEpoch int64                   `json:"lastCheckEpoch"`
+	Items          map[string]ManifestItem `json:"items"`
+}
+
+// ManifestItem represents an installed workshop item
+type ManifestItem struct {
+	Title            string   `json:"title"`
+	TimeUpdated      int64    `json:"timeUpdated"`
+	InstalledAtEpoch int64    `json:"installedAtEpoch"`
+	Paths            []string `json:"paths"`
+}
+
+// NewClient creates a new Steam Web API client
+func NewClient(cfg *config.Config) *Client {
+	return &Client{
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		apiBase: strings.TrimSuffix(cfg.GetString("STEAM_API_URL", steamAPIBase), "/"),
+	}
+}
+
+// apiPost posts a form to the ISteamRemoteStorage interface
+func (c *Client) apiPost(method string, form url.Values) ([]byte, error) {
+	resp, err := c.httpClient.PostForm(fmt.Sprintf("%s/ISteamRemoteStorage/%s/v1/", c.apiBase, method), form)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API request failed with status %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// GetFileDetails fetches metadata for the given published file IDs
+func (c *Client) GetFileDetails(ids []string) (map[string]FileDetails, error) {
+	form := url.Values{}
+	form.Set("itemcount", strconv.Itoa(len(ids)))
+	for i, id := range ids {
+		form.Set(fmt.Sprintf("publishedfileids[%d]", i), id)
+	}
+
+	data, err := c.apiPost("GetPublishedFileDetails", form)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Response struct {
+			PublishedFileDetails []FileDetails `json:"publishedfiledetails"`
+		} `json:"response"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+
+	details := make(map[string]FileDetails)
+	for _, d := range resp.Response.PublishedFileDetails {
+		if d.Result == 1 {
+			details[d.PublishedFileID] = d
+		}
+	}
+	return details, nil
+}
+
+// GetCollectionItems expands a collection into its child item IDs, in collection order
+func (c *Client) GetCollectionItems(collectionID string) ([]string, error) {
+	form := url.Values{}
+	form.Set("collectioncount", "1")
+	form.Set("publishedfileids[0]", collectionID)
+
+	data, err := c.apiPost("GetCollectionDetails", form)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Response struct {
+			CollectionDetails []struct {
+				Result   int `json:"result"`
+				Children []struct {
+					PublishedFileID string `json:"publishedfileid"`
+					SortOrder       int    `json:"sortorder"`
+				} `json:"children"`
+			} `json:"collectiondetails"`
+		} `json:"response"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+
+	if len(resp.Response.CollectionDetails) == 0 || resp.Response.CollectionDetails[0].Result != 1 {
+		return nil, fmt.Errorf("collection not found: %s", collectionID)
+	}
+
+	// Children carry an explicit sort order which defines the load order
+	children := resp.Response.CollectionDetails[0].Children
+	sort.SliceStable(children, func(i, j int) bool {
+		return children[i].SortOrder < children[j].SortOrder
+	})
+
+	ids := make([]string, len(children))
+	for i, child := range children {
+		ids[i] = child.PublishedFileID
+	}
+	return ids, nil
+}
+
+// Manager handles workshop item installation through SteamCMD
+type Manager struct {
+	client       *Client
+	appID        int
+	steamCmd     string
+	login        string
+	stateDir     string
+	manifestPath string
+	failOnError  bool
+	prune        bool
+}
+
+// NewManager creates a new workshop manager
+// appID is the workshop (client) app ID, which often differs from the dedicated server app ID
+// dataDir is where state and downloaded content are stored
+func NewManager(cfg *config.Config, appID int, steamCmd, dataDir string) (*Manager, error) {
+	stateDir := filepath.Join(dataDir, ".steam-workshop")
+
+	m := &Manager{
+		client:       NewClient(cfg),
+		appID:        appID,
+		steamCmd:     steamCmd,
+		login:        cfg.GetString("STEAM_WORKSHOP_LOGIN", "anonymous"),
+		stateDir:     stateDir,
+		manifestPath: filepath.Join(stateDir, "manifest.json"),
+		failOnError:  cfg.GetBool("STEAM_WORKSHOP_FAIL_ON_ERROR", false),
+		prune:        cfg.GetBool("STEAM_WORKSHOP_PRUNE", true),
+	}
+
+	if err := os.MkdirAll(stateDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory %s: %w", stateDir, err)
+	}
+
+	return m, nil
+}
+
+// InstallMods resolves, downloads and places the configured workshop items
+// itemRefs and collectionRefs are whitespace separated published file IDs;
+// collection children are appended after explicit items, keeping their order
+func (m *Manager) InstallMods(itemRefs, collectionRefs string, placer Placer) error {
+	ids := parseIDs(itemRefs)
+	for _, collectionID := range parseIDs(collectionRefs) {
+		output.Step(fmt.Sprintf("Resolving collection %s", collectionID))
+		children, err := m.client.GetCollectionItems(collectionID)
+		if err != nil {
+			output.Error(err.Error())
+			if m.failOnError {
+				return err
+			}
+			continue
+		}
+		output.SuccessWithMessage(fmt.Sprintf("%d item(s)", len(children)))
+		ids = append(ids, children...)
+	}
+	ids = dedupe(ids)
+
+	manifest := m.loadManifest()
+	if len(ids) == 0 {
+		if m.prune && len(manifest.Items) > 0 {
+			m.pruneItems(&manifest, map[string]bool{})
+			if err := m.saveManifest(&manifest); err != nil {
+				return err
+			}
+			if err := placer.Finalize(nil); err != nil {
+				return fmt.Errorf("failed to finalize workshop mods: %w", err)
+			}
+		}
+		output.Info("No workshop items configured")
+		return nil
+	}
+
+	details, err := m.client.GetFileDetails(ids)
+	if err != nil {
+		// Without metadata we can still install whatever steamcmd gives us
+		output.Warning(fmt.Sprintf("could not fetch workshop metadata: %v", err))
+		details = map[string]FileDetails{}
+	}
+
+	// Work out which items need a (re-)download
+	var stale []string
+	staleSet := make(map[string]bool)
+	for _, id := range ids {
+		existing, ok := manifest.Items[id]
+		d, known := details[id]
+		if !ok || !fileExists(m.contentDir(id)) || (known && d.TimeUpdated > existing.TimeUpdated) {
+			stale = append(stale, id)
+			staleSet[id] = true
+		}
+	}
+
+	if len(stale) > 0 {
+		output.Step(fmt.Sprintf("Downloading %d workshop item(s)", len(stale)))
+		fmt.Println()
+		if err := m.download(stale); err != nil {
+			output.Error(err.Error())
+			if m.failOnError {
+				return err
+			}
+		}
+	}
+
+	errors := 0
+	desired := make(map[string]bool)
+	var items []Item
+	for _, id := range ids {
+		d := details[id]
+		item := Item{
+			ID:          id,
+			Title:       d.Title,
+			TimeUpdated: d.TimeUpdated,
+			ContentDir:  m.contentDir(id),
+		}
+		if item.Title == "" {
+			item.Title = id
+		}
+
+		output.Step(fmt.Sprintf("Installing workshop item: %s", item.Title))
+		if existing, ok := manifest.Items[id]; ok && !staleSet[id] && pathsExist(existing.Paths) {
+			item.TimeUpdated = existing.TimeUpdated
+			desired[id] = true
+			items = append(items, item)
+			output.SuccessWithMessage("already installed")
+			continue
+		}
+		if !fileExists(item.ContentDir) {
+			output.Warning(fmt.Sprintf("content for %s was not downloaded", id))
+			errors++
+			continue
+		}
+
+		// Remove previously placed files so renamed or deleted files don't linger
+		if existing, ok := manifest.Items[id]; ok {
+			removePaths(existing.Paths)
+		}
+
+		paths, err := placer.Place(item)
+		if err != nil {
+			output.Warning(fmt.Sprintf("failed to install %s: %v", id, err))
+			errors++
+			continue
+		}
+
+		if item.TimeUpdated == 0 {
+			item.TimeUpdated = manifest.Items[id].TimeUpdated
+		}
+		manifest.Items[id] = ManifestItem{
+			Title:            item.Title,
+			TimeUpdated:      item.TimeUpdated,
+			InstalledAtEpoch: time.Now().Unix(),
+			Paths:            paths,
+		}
+		desired[id] = true
+		items = append(items, item)
+		output.Success()
+	}
+
+	if err := placer.Finalize(items); err != nil {
+		return fmt.Errorf("failed to finalize workshop mods: %w", err)
+	}
+
+	if m.prune {
+		m.pruneItems(&manifest, desired)
+	}
+
+	if err := m.saveManifest(&manifest); err != nil {
+		return err
+	}
+
+	if errors > 0 && m.failOnError {
+		return fmt.Errorf("%d workshop item(s) failed to install", errors)
+	}
+
+	return nil
+}
+
+// download fetches the given items with a single steamcmd invocation
+func (m *Manager) download(ids []string) error {
+	args := []string{
+		"+force_install_dir", m.stateDir,
+		"+login", m.login,
+	}
+	for _, id := range ids {
+		args = append(args, "+workshop_download_item", strconv.Itoa(m.appID), id, "validate")
+	}
+	args = append(args, "+quit")
+
+	cmd := exec.Command(m.steamCmd, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("steamcmd workshop download failed: %w", err)
+	}
+	return nil
+}
+
+// contentDir is where steamcmd places a downloaded item
+func (m *Manager) contentDir(id string) string {
+	return filepath.Join(m.stateDir, "steamapps", "workshop", "content", strconv.Itoa(m.appID), id)
+}
+
+// pruneItems removes items no longer in the config
+func (m *Manager) pruneItems(manifest *Manifest, desired map[string]bool) {
+	for id, item := range manifest.Items {
+		if !desired[id] {
+			removePaths(item.Paths)
+			os.RemoveAll(m.contentDir(id))
+			delete(manifest.Items, id)
+		}
+	}
+}
+
+// loadManifest loads or creates the manifest
+func (m *Manager) loadManifest() Manifest {
+	manifest := Manifest{
+		SchemaVersion: 1,
+		AppID:         m.appID,
+		Items:         make(map[string]ManifestItem),
+	}
+
+	data, err := os.ReadFile(m.manifestPath)
+	if err != nil {
+		return manifest
+	}
+
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return manifest
+	}
+
+	if manifest.Items == nil {
+		manifest.Items = make(map[string]ManifestItem)
+	}
+
+	return manifest
+}
+
+// saveManifest saves the manifest. It is written to a temporary file first,
+// since a lost manifest would forget every installed item.
+func (m *Manager) saveManifest(manifest *Manifest) error {
+	manifest.LastCheckEpoch = time.Now().Unix()
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode workshop manifest: %w", err)
+	}
+
+	tmp := m.manifestPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to save workshop manifest: %w", err)
+	}
+	if err := os.Rename(tmp, m.manifestPath); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to save workshop manifest: %w", err)
+	}
+	return nil
+}
+
+// Helper functions
+
+func parseIDs(input string) []string {
+	var ids []string
+	for _, line := range strings.Split(input, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, token := range strings.FieldsFunc(line, func(r rune) bool { return r == ' ' || r == ',' || r == '\t' }) {
+			if _, err := strconv.ParseUint(token, 10, 64); err != nil {
+				output.Warning(fmt.Sprintf("ignoring invalid workshop ID: %s", token))
+				continue
+			}
+			ids = append(ids, token)
+		}
+	}
+	return ids
+}
+
+func dedupe(ids []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+func removePaths(paths []string) {
+	for _, p := range paths {
+		os.RemoveAll(p)
+	}
+}
+
+func pathsExist(paths []string) bool {
+	for _, p := range paths {
+		if !fileExists(p) {
+			return false
+		}
+	}
+	return len(paths) > 0
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
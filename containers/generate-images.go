@@ -17,6 +17,7 @@ type Environment struct {
 	Base				string   `yaml:"base"`
 	Maintainer			string   `yaml:"maintainer"`
 	SteamAppID			string   `yaml:"steam_app_id"`
+	Game				string   `yaml:"game"`
 	AdditionalDeps		[]string `yaml:"additional_dependencies"`
 }
 
@@ -25,9 +26,22 @@ type Config struct {
 	Environment []Environment `yaml:"environment"`
 }
 
+// GameDefinition holds the parts of a gamekeeper game definition the images need
+type GameDefinition struct {
+	Install struct {
+		Steam struct {
+			AppID int `yaml:"app_id"`
+		} `yaml:"steam"`
+	} `yaml:"install"`
+}
+
+// definitionsDir holds the game definitions built into gamekeeper
+const definitionsDir = "gamekeeper/pkg/gamedef/games"
+
 // TemplateData is used to pass data to the Dockerfile template
 type TemplateData struct {
 	EnvironmentName		string
+	Game				string
 	Type				string
 	BaseDockerfile    	string
 	Maintainer         	string
@@ -82,6 +96,16 @@ func main() {
 		baseDockerfile := fmt.Sprintf("kubelize/game-servers:%s-%s%s", *baseVersion, environment.Base, *tagSuffix)
 		outputFileName := fmt.Sprintf("Dockerfile.%s", environment.DockerfileExt)
 
+		// The Steam app ID comes from the game definition unless set here
+		steamAppID := environment.SteamAppID
+		if steamAppID == "" && environment.Type == "steam" && environment.Game != "" {
+			steamAppID, err = definitionAppID(environment.Game)
+			if err != nil {
+				fmt.Printf("Error reading game definition for %s: %v\n", environment.Name, err)
+				continue
+			}
+		}
+
 		// Create the output file
 		outputFile, err := os.Create(fmt.Sprintf("./images/%s", outputFileName))
 		if err != nil {
@@ -90,13 +114,20 @@ func main() {
 		}
 		defer outputFile.Close()
 
+		// The entrypoint starts the game under its gamekeeper name
+		game := environment.Game
+		if game == "" {
+			game = environment.Name
+		}
+
 		// Prepare the template data
 		templateData := TemplateData{
 			EnvironmentName:	environment.Name,
+			Game:				game,
 			Type:				environment.Type,
 			BaseDockerfile: 	baseDockerfile,
 			Maintainer:     	environment.Maintainer,
-			SteamAppID: 		environment.SteamAppID,
+			SteamAppID: 		steamAppID,
 			AdditionalDeps: 	environment.AdditionalDeps,
 		}
 
@@ -110,3 +141,20 @@ func main() {
 		fmt.Printf("Dockerfile for %s created at ./images/%s\n", environment.Name, outputFileName)
 	}
 }
+
+// definitionAppID reads the Steam app ID from a game definition
+func definitionAppID(game string) (string, error) {
+	data, err := ioutil.ReadFile(fmt.Sprintf("%s/%s.yaml", definitionsDir, game))
+	if err != nil {
+		return "", err
+	}
+
+	var def GameDefinition
+	if err := yaml.Unmarshal(data, &def); err != nil {
+		return "", err
+	}
+	if def.Install.Steam.AppID == 0 {
+		return "", fmt.Errorf("no steam app_id in the %s definition", game)
+	}
+	return fmt.Sprintf("%d", def.Install.Steam.AppID), nil
+}
@@ -18,12 +18,10 @@ PVP=${PVP:-"true"}
 GAME_DIR="/home/kubelize/gameserver"
 cd $GAME_DIR
 
-# Download server jar if it doesn't exist
-if [ ! -f "$SERVER_JAR" ]; then
-    echo "Downloading Minecraft server version $MINECRAFT_VERSION..."
-    wget -O $SERVER_JAR "https://launcher.mojang.com/v1/objects/${MINECRAFT_VERSION}/${SERVER_JAR}" || \
-    echo "Note: You may need to manually download the server jar and mount it"
-fi
+# Download (or update) the server jar from the Mojang version manifest
+echo "Installing Minecraft server version $MINECRAFT_VERSION..."
+BASE_DIR=$GAME_DIR MINECRAFT_VERSION=$MINECRAFT_VERSION SERVER_JAR=$SERVER_JAR \
+    gamekeeper update --game minecraft
 
 # Accept EULA
 if [ ! -f "eula.txt" ]; then